@@ -1,24 +1,76 @@
 package app
 
 import (
+	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/domain"
 	"go_url_chortener_api/internal/env"
+	"go_url_chortener_api/internal/http-server/auth"
+	adminAudit "go_url_chortener_api/internal/http-server/handlers/admin/audit"
+	adminLinkList "go_url_chortener_api/internal/http-server/handlers/admin/links/list"
+	adminLinkStatus "go_url_chortener_api/internal/http-server/handlers/admin/links/status"
+	adminStats "go_url_chortener_api/internal/http-server/handlers/admin/stats"
+	adminUserList "go_url_chortener_api/internal/http-server/handlers/admin/users/list"
+	adminUserReset "go_url_chortener_api/internal/http-server/handlers/admin/users/reset"
+	adminUserSessions "go_url_chortener_api/internal/http-server/handlers/admin/users/sessions"
+	adminUserStatus "go_url_chortener_api/internal/http-server/handlers/admin/users/status"
+	apiKeyCreate "go_url_chortener_api/internal/http-server/handlers/apikeys/create"
+	apiKeyList "go_url_chortener_api/internal/http-server/handlers/apikeys/list"
+	apiKeyRemove "go_url_chortener_api/internal/http-server/handlers/apikeys/remove"
+	"go_url_chortener_api/internal/http-server/handlers/auth/email"
+	"go_url_chortener_api/internal/http-server/handlers/auth/logout"
+	"go_url_chortener_api/internal/http-server/handlers/auth/mfa"
+	"go_url_chortener_api/internal/http-server/handlers/auth/password/change"
+	"go_url_chortener_api/internal/http-server/handlers/auth/password/forgot"
+	"go_url_chortener_api/internal/http-server/handlers/auth/password/reset"
 	"go_url_chortener_api/internal/http-server/handlers/auth/signin"
 	"go_url_chortener_api/internal/http-server/handlers/auth/signup"
+	"go_url_chortener_api/internal/http-server/handlers/auth/verify"
 	"go_url_chortener_api/internal/http-server/handlers/del"
+	domainCreate "go_url_chortener_api/internal/http-server/handlers/domains/create"
+	domainList "go_url_chortener_api/internal/http-server/handlers/domains/list"
+	domainRemove "go_url_chortener_api/internal/http-server/handlers/domains/remove"
+	domainVerify "go_url_chortener_api/internal/http-server/handlers/domains/verify"
+	"go_url_chortener_api/internal/http-server/handlers/expand"
+	"go_url_chortener_api/internal/http-server/handlers/jwks"
+	pageGet "go_url_chortener_api/internal/http-server/handlers/pages/get"
+	pageList "go_url_chortener_api/internal/http-server/handlers/pages/list"
+	pageRemove "go_url_chortener_api/internal/http-server/handlers/pages/remove"
+	pageSave "go_url_chortener_api/internal/http-server/handlers/pages/save"
+	pageShow "go_url_chortener_api/internal/http-server/handlers/pages/show"
 	"go_url_chortener_api/internal/http-server/handlers/redirect"
 	"go_url_chortener_api/internal/http-server/handlers/refresh"
+	sessionList "go_url_chortener_api/internal/http-server/handlers/sessions/list"
+	sessionRemove "go_url_chortener_api/internal/http-server/handlers/sessions/remove"
+	"go_url_chortener_api/internal/http-server/handlers/url/qr"
 	"go_url_chortener_api/internal/http-server/handlers/url/save"
+	"go_url_chortener_api/internal/http-server/handlers/url/stats"
+	"go_url_chortener_api/internal/http-server/handlers/url/update"
+	"go_url_chortener_api/internal/http-server/middleware/audit"
 	"go_url_chortener_api/internal/http-server/middleware/myJwt"
+	"go_url_chortener_api/internal/http-server/middleware/ratelimit"
+	"go_url_chortener_api/internal/http-server/middleware/realip"
+	"go_url_chortener_api/internal/http-server/middleware/verified"
 	srv "go_url_chortener_api/internal/http-server/server"
+	"go_url_chortener_api/internal/http-server/view"
+	"go_url_chortener_api/internal/lib/clock"
 	"go_url_chortener_api/internal/lib/hash"
+	"go_url_chortener_api/internal/lib/jwtkeys"
 	"go_url_chortener_api/internal/lib/logger/sl"
 	"go_url_chortener_api/internal/lib/logger/slogpretty"
+	"go_url_chortener_api/internal/lib/mailer"
+	"go_url_chortener_api/internal/lib/ownership"
+	"go_url_chortener_api/internal/lib/password"
+	"go_url_chortener_api/internal/lib/revocation"
+	"go_url_chortener_api/internal/lib/totp"
 	"go_url_chortener_api/internal/storage/postgres"
 	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"time"
 )
 
 func Run(cfg *config.Config) {
@@ -32,9 +84,52 @@ func Run(cfg *config.Config) {
 		return
 	}
 
-	hasher := hash.NewSHA1Hasher(env.Salt)
+	if err := view.Load(cfg.TemplatesDir); err != nil {
+		log.Error("failed to load templates", sl.Err(err))
+		return
+	}
+
+	hasher, err := hash.New(&cfg.Auth.PasswordHash)
+	if err != nil {
+		log.Error("failed to init password hasher", sl.Err(err))
+		return
+	}
+
+	policy, err := password.NewPolicy(&cfg.Auth.PasswordPolicy)
+	if err != nil {
+		log.Error("failed to init password policy", sl.Err(err))
+		return
+	}
+
+	keys, err := loadKeys(log, cfg.Env, &cfg.Auth)
+	if err != nil {
+		log.Error("failed to load signing keys", sl.Err(err))
+		return
+	}
 
-	router := getRouter(log, storage, hasher)
+	revoked, err := revocationStore(&cfg.Auth, storage)
+	if err != nil {
+		log.Error("failed to init revocation store", sl.Err(err))
+		return
+	}
+
+	mail, err := mailer.Load(&cfg.Mail, cfg.Env)
+	if err != nil {
+		log.Error("failed to init mailer", sl.Err(err))
+		return
+	}
+
+	proxies, err := realip.Parse(cfg.HttpServer.TrustedProxies)
+	if err != nil {
+		log.Error("failed to parse trusted proxies", sl.Err(err))
+		return
+	}
+
+	router, err := getRouter(log, cfg, storage, hasher, policy, keys, revoked, mail, proxies)
+	if err != nil {
+		log.Error("failed to init router", sl.Err(err))
+		return
+	}
 
 	log.Info("starting server...",
 		slog.String("address", cfg.HttpServer.Address+":"+cfg.HttpServer.Port),
@@ -49,27 +144,154 @@ func Run(cfg *config.Config) {
 
 }
 
-func getRouter(log *slog.Logger, storage *postgres.Storage, hasher *hash.SHA1Hasher) *chi.Mux {
+func getRouter(log *slog.Logger, cfg *config.Config, storage *postgres.Storage, hasher hash.PasswordHasher, policy *password.Policy, keys *jwtkeys.Manager, revoked revocation.Store, mail mailer.Mailer, proxies realip.Proxies) (*chi.Mux, error) {
 	router := chi.NewRouter()
 	router.Use(middleware.RequestID)
-	router.Use(middleware.RealIP)
+	router.Use(realip.New(proxies))
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
-	router.Use(middleware.URLFormat)
+
+	tokens := myJwt.NewTokens(&cfg.Auth, keys, revoked)
+	clk := clock.New()
+	totpCodes := totp.New(clk)
+	signInLimits := signin.NewLimits(clk, &cfg.Auth.SignInThrottle)
+	mailLimit := ratelimit.New(log, &cfg.MailRateLimit)
+	resetCooldown := forgot.NewCooldown(clk, cfg.Auth.PasswordResetCooldown)
+	verifySender := verify.NewSender(keys, mail, cfg.Auth.VerifyTokenLifetime, cfg.HttpServer.BaseURL)
+	signIn, err := signin.New(log, storage, hasher, tokens, keys, &cfg.Auth, signInLimits)
+	if err != nil {
+		return nil, err
+	}
 
 	router.Route("/auth", func(r chi.Router) {
-		r.Post("/signup", signup.New(log, storage, hasher))
-		r.Post("/signin", signin.New(log, storage, hasher))
-		r.Get("/refresh", refresh.New(log, storage))
+		r.Post("/signup", signup.New(log, storage, hasher, verifySender, policy))
+		r.Post("/signin", signIn)
+		r.Post("/signin/mfa", signin.MFA(log, storage, totpCodes, tokens, keys, &cfg.Auth, signInLimits))
+		r.Get("/verify", verify.New(log, storage, keys))
+		r.Post("/verify/resend", verify.Resend(log, storage, verifySender))
+		r.With(mailLimit).Post("/password/forgot", forgot.New(log, storage, mail, resetCooldown, &cfg.Auth, &cfg.HttpServer))
+		r.Get("/password/reset", reset.Page(log))
+		r.Post("/password/reset", reset.New(log, storage, hasher, tokens, policy))
+		r.Get("/refresh", refresh.New(log, storage, tokens, keys))
+		r.Post("/logout", logout.New(log, storage, tokens))
+
+		r.Group(func(r chi.Router) {
+			r.Use(myJwt.JwtMiddleware(log, tokens))
+			r.Post("/logout-all", logout.All(log, storage, tokens))
+			r.Post("/password/change", change.New(log, storage, hasher, tokens, policy))
+			r.Post("/email/change", email.New(log, storage, hasher, tokens, verifySender))
+			r.Post("/mfa/enroll", mfa.Enroll(log, storage, hasher, cfg.Auth.Issuer))
+			r.Post("/mfa/confirm", mfa.Confirm(log, storage, totpCodes))
+			r.Get("/sessions", sessionList.New(log, storage))
+			r.Delete("/sessions/{id}", sessionRemove.New(log, storage, tokens))
+		})
 	})
+	router.Get("/.well-known/jwks.json", jwks.New(keys))
+
+	requireVerified := func(next http.Handler) http.Handler { return next }
+	if cfg.Auth.RequireVerifiedEmail {
+		requireVerified = verified.New(log, storage)
+	}
 
 	router.Route("/url", func(r chi.Router) {
-		r.Use(myJwt.JwtMiddleware(log))
-		r.Post("/", save.New(log, storage))
-		r.Delete("/{alias}", del.New(log, storage))
+		r.Use(myJwt.AuthMiddleware(log, tokens, storage))
+		r.With(auth.RequireScope(auth.ScopeLinksWrite), requireVerified).Post("/", save.New(log, storage))
+		r.With(auth.RequireScope(auth.ScopeLinksWrite)).Patch("/{alias}", update.New(log, storage))
+		r.With(auth.RequireScope(auth.ScopeLinksWrite)).Delete("/{alias}", del.New(log, storage))
+		r.With(auth.RequireScope(auth.ScopeStatsRead)).Get("/{alias}/stats", stats.New(log, storage))
+		r.With(auth.RequireScope(auth.ScopeLinksRead)).Get("/{alias}/qr", qr.New(log, storage, clk, &cfg.HttpServer))
+	})
+
+	router.Route("/api-keys", func(r chi.Router) {
+		r.Use(myJwt.JwtMiddleware(log, tokens))
+		r.Post("/", apiKeyCreate.New(log, storage))
+		r.Get("/", apiKeyList.New(log, storage))
+		r.Delete("/{id}", apiKeyRemove.New(log, storage))
+	})
+
+	// Admin requests are audited before the role check, so refused
+	// attempts are on record too.
+	router.Route("/admin", func(r chi.Router) {
+		r.Use(myJwt.JwtMiddleware(log, tokens))
+		r.Use(audit.New(log, storage))
+		r.Use(auth.RequireRole(domain.RoleAdmin))
+		r.Get("/users", adminUserList.New(log, storage))
+		r.Post("/users/{id}/disable", adminUserStatus.New(log, storage, tokens, true))
+		r.Post("/users/{id}/enable", adminUserStatus.New(log, storage, tokens, false))
+		r.Post("/users/{id}/password-reset", adminUserReset.New(log, storage, tokens))
+		r.Delete("/users/{id}/sessions", adminUserSessions.New(log, storage, tokens))
+		r.Get("/links", adminLinkList.New(log, storage))
+		r.Post("/links/{id}/disable", adminLinkStatus.New(log, storage, true))
+		r.Post("/links/{id}/enable", adminLinkStatus.New(log, storage, false))
+		r.Get("/stats", adminStats.New(log, storage))
+		r.Get("/audit", adminAudit.New(log, storage))
+	})
+
+	verifier := ownership.NewVerifier(net.DefaultResolver, &http.Client{
+		Timeout: 10 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
 	})
-	router.Get("/{alias}", redirect.New(log, storage))
-	return router
+
+	router.Route("/domains", func(r chi.Router) {
+		r.Use(myJwt.JwtMiddleware(log, tokens))
+		r.Post("/", domainCreate.New(log, storage))
+		r.Get("/", domainList.New(log, storage))
+		r.Post("/{id}/verify", domainVerify.New(log, storage, verifier))
+		r.Delete("/{id}", domainRemove.New(log, storage))
+	})
+
+	router.Route("/pages", func(r chi.Router) {
+		r.Use(myJwt.JwtMiddleware(log, tokens))
+		r.Post("/", pageSave.New(log, storage))
+		r.Get("/", pageList.New(log, storage))
+		r.Get("/{id}", pageGet.New(log, storage))
+		r.Put("/{id}", pageSave.New(log, storage))
+		r.Delete("/{id}", pageRemove.New(log, storage))
+	})
+	router.Get("/p/{slug}", pageShow.New(log, storage, &cfg.HttpServer))
+
+	expandLimit := ratelimit.New(log, &cfg.ExpandRateLimit)
+
+	router.Route("/api/expand", func(r chi.Router) {
+		r.Use(expandLimit)
+		r.Get("/", expand.New(log, storage, clk, &cfg.HttpServer))
+		r.Options("/", expand.Options)
+	})
+
+	// Aliases are resolved on the request host, so links on custom domains
+	// are served by the same route.
+	router.Get("/{alias}", redirect.New(log, storage, clk, &cfg.Redirect))
+	router.With(expandLimit).Head("/{alias}", expand.New(log, storage, clk, &cfg.HttpServer))
+	router.Options("/{alias}", expand.Options)
+	return router, nil
+}
+
+// loadKeys falls back to a throwaway key when none are configured, so the
+// service still starts locally. Tokens then do not survive a restart, and
+// replicas would not accept each other's tokens, so other environments
+// must configure their keys.
+func loadKeys(log *slog.Logger, environment string, cfg *config.Auth) (*jwtkeys.Manager, error) {
+	const fn = "app.loadKeys"
+	if len(cfg.Keys) == 0 {
+		if environment != env.EnvLocal {
+			return nil, fmt.Errorf("%s : %w", fn, jwtkeys.ErrNoSigningKey)
+		}
+		log.Warn("no signing keys configured, using an ephemeral key")
+		return jwtkeys.Ephemeral()
+	}
+	return jwtkeys.Load(cfg)
+}
+
+func revocationStore(cfg *config.Auth, storage *postgres.Storage) (revocation.Store, error) {
+	switch cfg.RevocationStore {
+	case "memory":
+		return revocation.NewMemory(clock.New(), myJwt.TokenLifetime), nil
+	case "postgres":
+		return storage, nil
+	}
+	return nil, fmt.Errorf("unknown revocation store %q", cfg.RevocationStore)
 }
 
 func setupLogger(environment string) *slog.Logger {
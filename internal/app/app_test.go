@@ -0,0 +1,50 @@
+package app
+
+import (
+	"encoding/json"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/lib/clock"
+	"go_url_chortener_api/internal/lib/hash"
+	"go_url_chortener_api/internal/lib/jwtkeys"
+	"go_url_chortener_api/internal/lib/mailer"
+	"go_url_chortener_api/internal/lib/password"
+	"go_url_chortener_api/internal/lib/revocation"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestJWKSRoute(t *testing.T) {
+	keys, err := jwtkeys.Ephemeral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, err := password.NewPolicy(&config.PasswordPolicy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		ExpandRateLimit: config.RateLimit{RPS: 1, Burst: 1},
+		MailRateLimit:   config.RateLimit{RPS: 1, Burst: 1},
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router, err := getRouter(log, cfg, nil, hash.NewSHA1Hasher(4), policy, keys,
+		revocation.NewMemory(clock.New(), time.Minute), mailer.NewWriter(io.Discard, ""), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var set jwtkeys.JWKSet
+	if err := json.NewDecoder(rec.Body).Decode(&set); err != nil || len(set.Keys) == 0 {
+		t.Errorf("key set = %+v, err = %v", set, err)
+	}
+}
@@ -1,11 +1,16 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
 	"go_url_chortener_api/internal/env"
 	"log"
+	"net"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -13,6 +18,15 @@ type Config struct {
 	Env        string     `yaml:"env"`
 	HttpServer HttpServer `yaml:"http_server"`
 	Storage    Storage    `yaml:"storage"`
+	Auth       Auth       `yaml:"auth"`
+	Redirect   Redirect   `yaml:"redirect"`
+	Mail       Mail       `yaml:"mail"`
+	// ExpandRateLimit limits the public link expansion endpoints per client IP.
+	ExpandRateLimit RateLimit `yaml:"expand_rate_limit"`
+	// MailRateLimit limits the public endpoints that send mail per client IP.
+	MailRateLimit RateLimit `yaml:"mail_rate_limit"`
+	// TemplatesDir overrides the built-in HTML templates, see view.Load.
+	TemplatesDir string `yaml:"templates_dir"`
 }
 
 type HttpServer struct {
@@ -20,6 +34,145 @@ type HttpServer struct {
 	Port        string        `yaml:"port"`
 	Timeout     time.Duration `yaml:"timeout"`
 	IdleTimeout time.Duration `yaml:"idle_timeout"`
+	// BaseURL is the public address short links are served on, e.g.
+	// https://sho.rt. Links in emails and QR codes are built from it, never
+	// from the request host, so it is required outside the local
+	// environment; locally it defaults to localhost and Port.
+	BaseURL string `yaml:"base_url"`
+	// TrustedProxies lists the addresses or CIDR ranges of the reverse
+	// proxies in front of the service. The client address is taken from
+	// X-Forwarded-For or X-Real-IP only on requests coming from them.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// Validate checks BaseURL, after the local default has been applied.
+func (c *HttpServer) Validate() error {
+	if c.BaseURL == "" {
+		return errors.New("base_url is required")
+	}
+	u, err := url.Parse(c.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("base_url must be an absolute http(s) URL, got %q", c.BaseURL)
+	}
+	return nil
+}
+
+type Auth struct {
+	// Issuer and Audience are set on issued tokens and required on
+	// tokens presented to the API.
+	Issuer   string `yaml:"issuer" env-default:"go_url_shortener"`
+	Audience string `yaml:"audience" env-default:"go_url_shortener_api"`
+	// SigningKey is the id of the key new tokens are signed with. The other
+	// keys are still accepted, which allows rotating keys without logging
+	// everybody out.
+	SigningKey string   `yaml:"signing_key"`
+	Keys       []JWTKey `yaml:"keys"`
+	// RevocationStore is where revoked access tokens are kept: "memory",
+	// or "postgres" to share them between instances.
+	RevocationStore string `yaml:"revocation_store" env-default:"memory"`
+	// RequireVerifiedEmail keeps users who have not confirmed their email
+	// address from signing in and creating links.
+	RequireVerifiedEmail bool `yaml:"require_verified_email" env-default:"false"`
+	// VerifyTokenLifetime is how long email verification links stay valid.
+	VerifyTokenLifetime time.Duration `yaml:"verify_token_lifetime" env-default:"24h"`
+	// PasswordResetLifetime is how long password reset links stay valid.
+	PasswordResetLifetime time.Duration `yaml:"password_reset_lifetime" env-default:"30m"`
+	// PasswordResetCooldown is how long after a reset mail another one is
+	// sent to the same address.
+	PasswordResetCooldown time.Duration  `yaml:"password_reset_cooldown" env-default:"5m"`
+	SignInThrottle        SignInThrottle `yaml:"sign_in_throttle"`
+	PasswordHash          PasswordHash   `yaml:"password_hash"`
+	PasswordPolicy        PasswordPolicy `yaml:"password_policy"`
+}
+
+// PasswordPolicy is checked whenever a password is set.
+type PasswordPolicy struct {
+	MinLength     int  `yaml:"min_length" env-default:"8"`
+	MaxLength     int  `yaml:"max_length" env-default:"128"`
+	RequireUpper  bool `yaml:"require_upper" env-default:"true"`
+	RequireLower  bool `yaml:"require_lower" env-default:"true"`
+	RequireDigit  bool `yaml:"require_digit" env-default:"true"`
+	RequireSymbol bool `yaml:"require_symbol" env-default:"true"`
+	// MaxRepeat is the longest run of one character allowed, 0 for any.
+	MaxRepeat int `yaml:"max_repeat" env-default:"3"`
+	// BreachedDir holds breached password hashes split into SHA-1 prefix
+	// files, see password.BreachedDir. The check is off when it is empty.
+	BreachedDir string `yaml:"breached_dir"`
+}
+
+// PasswordHash configures how new passwords are hashed. Hashes in other
+// formats keep verifying and are replaced on the next sign-in. The
+// benchmarks of the hash package help pick the argon2id cost.
+type PasswordHash struct {
+	// Algorithm is "argon2id" or "bcrypt".
+	Algorithm string `yaml:"algorithm" env-default:"argon2id"`
+	// Memory is in KiB.
+	Memory      uint32 `yaml:"memory" env-default:"65536"`
+	Iterations  uint32 `yaml:"iterations" env-default:"3"`
+	Parallelism uint8  `yaml:"parallelism" env-default:"2"`
+	BcryptCost  int    `yaml:"bcrypt_cost" env-default:"12"`
+}
+
+// SignInThrottle slows down password guessing. Failed sign-ins are counted
+// per account and per client IP; past the threshold every further attempt
+// waits, from BaseDelay doubling up to MaxDelay. LockoutThreshold failures
+// lock the account for LockoutDuration. Failures are forgotten after
+// ResetAfter without any.
+type SignInThrottle struct {
+	AccountThreshold int           `yaml:"account_threshold" env-default:"5"`
+	IPThreshold      int           `yaml:"ip_threshold" env-default:"20"`
+	BaseDelay        time.Duration `yaml:"base_delay" env-default:"1s"`
+	MaxDelay         time.Duration `yaml:"max_delay" env-default:"5m"`
+	LockoutThreshold int           `yaml:"lockout_threshold" env-default:"10"`
+	LockoutDuration  time.Duration `yaml:"lockout_duration" env-default:"15m"`
+	ResetAfter       time.Duration `yaml:"reset_after" env-default:"24h"`
+}
+
+// JWTKey is an RSA or Ed25519 key in a PEM file. Keys that are only kept
+// for verification may be given as public keys.
+type JWTKey struct {
+	Id   string `yaml:"id"`
+	Path string `yaml:"path"`
+}
+
+type Mail struct {
+	// Transport is "stdout", "file" to append messages to Path, or "smtp".
+	// Messages carry live links, so it defaults to stdout only locally and
+	// must be set in other environments.
+	Transport string `yaml:"transport"`
+	From      string `yaml:"from" env-default:"no-reply@localhost"`
+	Path      string `yaml:"path"`
+	SMTP      SMTP   `yaml:"smtp"`
+}
+
+type SMTP struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port" env-default:"587"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type Redirect struct {
+	// UnfurlPreview serves link previewers (Slack, Twitter, iMessage...)
+	// an OpenGraph page describing the link instead of a redirect.
+	UnfurlPreview bool `yaml:"unfurl_preview" env-default:"false"`
+}
+
+type RateLimit struct {
+	RPS   float64 `yaml:"rps" env-default:"1"`
+	Burst int     `yaml:"burst" env-default:"20"`
+}
+
+// Validate rejects limits that would never let a request through or never
+// refill the bucket.
+func (c *RateLimit) Validate() error {
+	if c.RPS <= 0 {
+		return fmt.Errorf("rps must be positive, got %v", c.RPS)
+	}
+	if c.Burst < 1 {
+		return fmt.Errorf("burst must be at least 1, got %d", c.Burst)
+	}
+	return nil
 }
 
 type Storage struct {
@@ -54,5 +207,18 @@ func MustLoad(environment string) *Config {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if cfg.HttpServer.BaseURL == "" && cfg.Env == env.EnvLocal {
+		cfg.HttpServer.BaseURL = "http://" + net.JoinHostPort("localhost", cfg.HttpServer.Port)
+	}
+	cfg.HttpServer.BaseURL = strings.TrimSuffix(cfg.HttpServer.BaseURL, "/")
+	if err := cfg.HttpServer.Validate(); err != nil {
+		log.Fatalf("http_server: %v", err)
+	}
+	if err := cfg.ExpandRateLimit.Validate(); err != nil {
+		log.Fatalf("expand_rate_limit: %v", err)
+	}
+	if err := cfg.MailRateLimit.Validate(); err != nil {
+		log.Fatalf("mail_rate_limit: %v", err)
+	}
 	return cfg
 }
@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// AuditEntry records one request made through the admin API.
+type AuditEntry struct {
+	Id      int    `json:"id"`
+	ActorId int    `json:"actorId"`
+	Action  string `json:"action"`
+	// TargetId is the id in the request path, if any.
+	TargetId  string    `json:"targetId,omitempty"`
+	Query     string    `json:"query,omitempty"`
+	Status    int       `json:"status"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SystemStats are the system-wide counters shown to admins.
+type SystemStats struct {
+	Users          int   `json:"users"`
+	DisabledUsers  int   `json:"disabledUsers"`
+	Links          int   `json:"links"`
+	DisabledLinks  int   `json:"disabledLinks"`
+	Clicks         int64 `json:"clicks"`
+	Domains        int   `json:"domains"`
+	Pages          int   `json:"pages"`
+	ActiveSessions int   `json:"activeSessions"`
+	APIKeys        int   `json:"apiKeys"`
+}
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	VerifyDNS  = "dns"
+	VerifyHTTP = "http"
+)
+
+// Domain is a custom host owned by a user, e.g. go.acme.com. Links created
+// on a domain only resolve once the owner proved control over it.
+type Domain struct {
+	Id         int        `json:"id"`
+	Host       string     `json:"host"`
+	UserId     int        `json:"userId"`
+	Method     string     `json:"method"`
+	Token      string     `json:"token"`
+	VerifiedAt *time.Time `json:"verifiedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func (d *Domain) Verified() bool {
+	return d.VerifiedAt != nil
+}
+
+// NormalizeHost lowercases a host and strips the port and trailing dot,
+// so values from the Host header and from the API compare equal.
+func NormalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+const (
+	ThemeLight = "light"
+	ThemeDark  = "dark"
+)
+
+// Page is a public link-in-bio page listing some of its owner's links.
+type Page struct {
+	Id        int        `json:"id"`
+	UserId    int        `json:"userId"`
+	Slug      string     `json:"slug"`
+	Title     string     `json:"title"`
+	Theme     string     `json:"theme"`
+	Links     []PageLink `json:"links"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// PageLink is a link listed on a page. Links are shown by ascending Position.
+type PageLink struct {
+	Id       int    `json:"id,omitempty"`
+	URLId    int    `json:"urlId"`
+	Alias    string `json:"alias"`
+	Host     string `json:"host,omitempty"`
+	Title    string `json:"title"`
+	Position int    `json:"position"`
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// Session is one signed-in device. It lives as long as the refresh token
+// family started by the sign-in.
+type Session struct {
+	Id         int        `json:"id"`
+	UserId     int        `json:"-"`
+	FamilyId   string     `json:"-"`
+	UserAgent  string     `json:"device"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt time.Time  `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
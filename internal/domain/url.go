@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+type URL struct {
+	Id        int        `json:"id,omitempty"`
+	Alias     string     `json:"alias"`
+	URL       string     `json:"url"`
+	UserId    int        `json:"userId,omitempty"`
+	DomainId  int        `json:"domainId,omitempty"`
+	Host      string     `json:"host,omitempty"`
+	Title     string     `json:"title,omitempty"`
+	Preview   bool       `json:"preview"`
+	OG        OpenGraph  `json:"og"`
+	Clicks    int64      `json:"clicks"`
+	CreatedAt time.Time  `json:"createdAt"`
+	StartsAt  *time.Time `json:"startsAt,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Disabled  bool       `json:"disabled"`
+	Variants  []Variant  `json:"variants,omitempty"`
+	Windows   []Window   `json:"windows,omitempty"`
+}
+
+const (
+	LinkActive    = "active"
+	LinkScheduled = "scheduled"
+	LinkExpired   = "expired"
+	LinkDisabled  = "disabled"
+)
+
+// Status tells whether the link can be followed at t and if not, why.
+func (u *URL) Status(t time.Time) string {
+	switch {
+	case u.Disabled:
+		return LinkDisabled
+	case u.Expired(t):
+		return LinkExpired
+	case u.StartsAt != nil && t.Before(*u.StartsAt):
+		return LinkScheduled
+	}
+	return LinkActive
+}
+
+// ActiveWindow returns the first scheduled window containing t.
+func (u *URL) ActiveWindow(t time.Time) *Window {
+	for i := range u.Windows {
+		if u.Windows[i].Contains(t) {
+			return &u.Windows[i]
+		}
+	}
+	return nil
+}
+
+// Expired reports whether the link stopped working at t.
+func (u *URL) Expired(t time.Time) bool {
+	return u.ExpiresAt != nil && !t.Before(*u.ExpiresAt)
+}
+
+// ShortURL is the public address of the link. Links on a custom domain
+// live on that domain, the others on baseURL.
+func (u *URL) ShortURL(baseURL string) string {
+	if u.Host != "" {
+		return "https://" + u.Host + "/" + u.Alias
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/" + u.Alias
+}
+
+type Variant struct {
+	Id     int    `json:"id,omitempty"`
+	URLId  int    `json:"urlId,omitempty"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+	Clicks int64  `json:"clicks"`
+}
+
+// Window sends visitors to URL between StartsAt and EndsAt.
+// A nil bound leaves that side of the window open.
+type Window struct {
+	Id       int        `json:"id,omitempty"`
+	URLId    int        `json:"urlId,omitempty"`
+	StartsAt *time.Time `json:"startsAt,omitempty"`
+	EndsAt   *time.Time `json:"endsAt,omitempty"`
+	URL      string     `json:"url"`
+}
+
+// Contains reports whether t falls inside the window.
+func (w Window) Contains(t time.Time) bool {
+	if w.StartsAt != nil && t.Before(*w.StartsAt) {
+		return false
+	}
+	if w.EndsAt != nil && !t.Before(*w.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// OpenGraph is the social card shown when the link is shared.
+type OpenGraph struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+}
+
+func (o OpenGraph) IsEmpty() bool {
+	return o.Title == "" && o.Description == "" && o.Image == ""
+}
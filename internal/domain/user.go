@@ -1,7 +1,32 @@
 package domain
 
+import "time"
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	Id          int    `json:"id,omitempty"`
 	Email       string `json:"email"`
-	EncPassword string `json:"encPassword"`
+	EncPassword string `json:"-"`
+	Role        string `json:"role"`
+	// Disabled accounts cannot sign in or use their API keys.
+	Disabled bool `json:"disabled"`
+	// PasswordResetRequired blocks sign-in until the password is reset.
+	PasswordResetRequired bool       `json:"passwordResetRequired"`
+	EmailVerifiedAt       *time.Time `json:"emailVerifiedAt,omitempty"`
+	// MFAEnabled users confirm sign-ins with a TOTP or recovery code.
+	MFAEnabled bool `json:"mfaEnabled"`
+	// TOTPSecret is set on enrollment, before it is confirmed.
+	TOTPSecret string `json:"-"`
+	// TOTPLastStep is the period of the last accepted code, which cannot
+	// be used again.
+	TOTPLastStep int64     `json:"-"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
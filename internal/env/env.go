@@ -3,6 +3,4 @@ package env
 const (
 	EnvLocal = "local"
 	EnvDev   = "dev"
-
-	Salt = 5
 )
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"net/http"
+	"time"
+)
+
+// Scopes an API key can be limited to.
+const (
+	ScopeLinksRead  = "links:read"
+	ScopeLinksWrite = "links:write"
+	ScopeStatsRead  = "stats:read"
+)
+
+// UserScopes are granted to users signed in with a password.
+var UserScopes = []string{ScopeLinksRead, ScopeLinksWrite, ScopeStatsRead}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserId int
+	Email  string
+	// Role is empty for API keys, so they never pass RequireRole.
+	Role string
+	// SessionId is the session the access token was issued to.
+	SessionId int
+	// TokenId and ExpiresAt identify the access token, to revoke it.
+	TokenId   string
+	ExpiresAt time.Time
+	// APIKeyId is set when the caller authenticated with an API key.
+	APIKeyId int
+	Scopes   []string
+}
+
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithUser returns a copy of ctx carrying the principal.
+func WithUser(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// UserFrom returns the principal stored by the authentication middleware.
+// It is always present in handlers mounted behind that middleware.
+func UserFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// RequireScope lets requests through only if the principal has every one
+// of the scopes. It must be mounted after the authentication middleware.
+func RequireScope(scopes ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, _ := UserFrom(r.Context())
+			for _, scope := range scopes {
+				if !p.HasScope(scope) {
+					customJson.WriteJson(w, http.StatusForbidden, resp.Error("missing scope "+scope))
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole lets requests through only if the principal has the role.
+// It must be mounted after the authentication middleware.
+func RequireRole(role string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, _ := UserFrom(r.Context())
+			if p.Role != role {
+				customJson.WriteJson(w, http.StatusForbidden, resp.Error("permission denied"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequire(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tests := []struct {
+		name       string
+		principal  Principal
+		middleware func(http.Handler) http.Handler
+		want       int
+	}{
+		{"scope granted", Principal{Scopes: UserScopes}, RequireScope(ScopeLinksWrite), http.StatusOK},
+		{"scope missing", Principal{Scopes: []string{ScopeLinksRead}}, RequireScope(ScopeLinksWrite), http.StatusForbidden},
+		{"all scopes needed", Principal{Scopes: []string{ScopeLinksRead}}, RequireScope(ScopeLinksRead, ScopeStatsRead), http.StatusForbidden},
+		{"role granted", Principal{Role: "admin"}, RequireRole("admin"), http.StatusOK},
+		{"role missing", Principal{Role: "user"}, RequireRole("admin"), http.StatusForbidden},
+		{"api key has no role", Principal{APIKeyId: 1, Scopes: UserScopes}, RequireRole("admin"), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r = r.WithContext(WithUser(r.Context(), tt.principal))
+		w := httptest.NewRecorder()
+		tt.middleware(ok).ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
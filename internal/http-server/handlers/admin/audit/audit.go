@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/customJson"
+	"go_url_chortener_api/internal/lib/api/request"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"log/slog"
+	"net/http"
+)
+
+type Response struct {
+	resp.Response
+	Entries []domain.AuditEntry `json:"entries"`
+}
+
+type AuditLogGetter interface {
+	GetAuditLog(limit int, offset int) ([]domain.AuditEntry, error)
+}
+
+func New(log *slog.Logger, auditLogGetter AuditLogGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.admin.audit.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		limit, offset, err := request.Page(r)
+		if err != nil {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error(err.Error()))
+			return
+		}
+
+		entries, err := auditLogGetter.GetAuditLog(limit, offset)
+		if err != nil {
+			log.Error("failed to get audit log", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		customJson.WriteJson(w, http.StatusOK, Response{
+			Response: resp.OK(),
+			Entries:  entries,
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package list
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/customJson"
+	"go_url_chortener_api/internal/lib/api/request"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"log/slog"
+	"net/http"
+)
+
+type Response struct {
+	resp.Response
+	Links []domain.URL `json:"links"`
+}
+
+type URLSearcher interface {
+	SearchURLs(q string, limit int, offset int) ([]domain.URL, error)
+}
+
+// New lists links of all users, filtered by a fragment of the alias or
+// destination in the q parameter.
+func New(log *slog.Logger, urlSearcher URLSearcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.admin.links.list.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		limit, offset, err := request.Page(r)
+		if err != nil {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error(err.Error()))
+			return
+		}
+
+		links, err := urlSearcher.SearchURLs(r.URL.Query().Get("q"), limit, offset)
+		if err != nil {
+			log.Error("failed to search links", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		customJson.WriteJson(w, http.StatusOK, Response{
+			Response: resp.OK(),
+			Links:    links,
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package status
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type URLDisabler interface {
+	SetURLDisabled(id int, disabled bool) error
+}
+
+// New disables or re-enables the link with the id in the path. Disabled
+// links answer 451 to visitors.
+func New(log *slog.Logger, urlDisabler URLDisabler, disabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.admin.links.status.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid link id"))
+			return
+		}
+
+		err = urlDisabler.SetURLDisabled(id, disabled)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			customJson.WriteJson(w, http.StatusNotFound, resp.Error("link not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update link", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		log.Info("link status changed", slog.Int("url_id", id), slog.Bool("disabled", disabled))
+
+		customJson.WriteJson(w, http.StatusOK, resp.OK())
+	}
+}
@@ -0,0 +1,68 @@
+package status
+
+import (
+	"github.com/go-chi/chi/v5"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/handlers/redirect"
+	"go_url_chortener_api/internal/lib/clock"
+	"go_url_chortener_api/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type memStore struct{ url *domain.URL }
+
+func (m *memStore) SetURLDisabled(id int, disabled bool) error {
+	if id != m.url.Id {
+		return storage.ErrURLNotFound
+	}
+	m.url.Disabled = disabled
+	return nil
+}
+
+func (m *memStore) GetURL(_ string, alias string) (*domain.URL, error) {
+	if alias != m.url.Alias {
+		return nil, storage.ErrURLNotFound
+	}
+	u := *m.url
+	return &u, nil
+}
+
+func (m *memStore) IncrementClicks(int, int) error { return nil }
+
+func TestDisabledLinkIsUnavailable(t *testing.T) {
+	store := &memStore{url: &domain.URL{Id: 3, Alias: "promo", URL: "https://example.com"}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	router := chi.NewRouter()
+	router.Post("/admin/links/{id}/disable", New(log, store, true))
+	router.Post("/admin/links/{id}/enable", New(log, store, false))
+	router.Get("/{alias}", redirect.New(log, store, clock.New(), &config.Redirect{}))
+
+	serve := func(method, path string) int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec.Code
+	}
+
+	steps := []struct {
+		method, path string
+		status       int
+	}{
+		{http.MethodGet, "/promo", http.StatusFound},
+		{http.MethodPost, "/admin/links/3/disable", http.StatusOK},
+		{http.MethodGet, "/promo", http.StatusUnavailableForLegalReasons},
+		{http.MethodPost, "/admin/links/3/enable", http.StatusOK},
+		{http.MethodGet, "/promo", http.StatusFound},
+		{http.MethodPost, "/admin/links/4/disable", http.StatusNotFound},
+	}
+	for _, s := range steps {
+		if code := serve(s.method, s.path); code != s.status {
+			t.Fatalf("%s %s: got %d, want %d", s.method, s.path, code, s.status)
+		}
+	}
+}
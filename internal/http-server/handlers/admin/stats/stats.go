@@ -0,0 +1,42 @@
+package stats
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"log/slog"
+	"net/http"
+)
+
+type Response struct {
+	resp.Response
+	Stats *domain.SystemStats `json:"stats"`
+}
+
+type StatsGetter interface {
+	GetSystemStats() (*domain.SystemStats, error)
+}
+
+func New(log *slog.Logger, statsGetter StatsGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.admin.stats.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		st, err := statsGetter.GetSystemStats()
+		if err != nil {
+			log.Error("failed to get stats", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		customJson.WriteJson(w, http.StatusOK, Response{
+			Response: resp.OK(),
+			Stats:    st,
+		})
+	}
+}
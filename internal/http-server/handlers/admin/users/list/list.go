@@ -0,0 +1,50 @@
+package list
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/customJson"
+	"go_url_chortener_api/internal/lib/api/request"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"log/slog"
+	"net/http"
+)
+
+type Response struct {
+	resp.Response
+	Users []domain.User `json:"users"`
+}
+
+type UserSearcher interface {
+	SearchUsers(q string, limit int, offset int) ([]domain.User, error)
+}
+
+// New lists users, filtered by an email fragment in the q parameter.
+func New(log *slog.Logger, userSearcher UserSearcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.admin.users.list.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		limit, offset, err := request.Page(r)
+		if err != nil {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error(err.Error()))
+			return
+		}
+
+		users, err := userSearcher.SearchUsers(r.URL.Query().Get("q"), limit, offset)
+		if err != nil {
+			log.Error("failed to search users", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		customJson.WriteJson(w, http.StatusOK, Response{
+			Response: resp.OK(),
+			Users:    users,
+		})
+	}
+}
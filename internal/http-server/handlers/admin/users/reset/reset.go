@@ -0,0 +1,63 @@
+package reset
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/http-server/customJson"
+	"go_url_chortener_api/internal/http-server/middleware/myJwt"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type ResetRequirer interface {
+	SetPasswordResetRequired(id int, required bool) error
+	RevokeSessionsByUser(userId int) error
+}
+
+// New forces the user with the id in the path to reset their password: they
+// are signed out and cannot sign in again until they do.
+func New(log *slog.Logger, resetRequirer ResetRequirer, tokens *myJwt.Tokens) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.admin.users.reset.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid user id"))
+			return
+		}
+
+		err = resetRequirer.SetPasswordResetRequired(id, true)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			customJson.WriteJson(w, http.StatusNotFound, resp.Error("user not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update user", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		if err := resetRequirer.RevokeSessionsByUser(id); err != nil {
+			log.Error("failed to revoke sessions", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		if err := tokens.RevokeUser(id); err != nil {
+			log.Error("failed to revoke access tokens", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		log.Info("password reset required", slog.Int("user_id", id))
+
+		customJson.WriteJson(w, http.StatusOK, resp.OK())
+	}
+}
@@ -0,0 +1,48 @@
+package sessions
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/http-server/customJson"
+	"go_url_chortener_api/internal/http-server/middleware/myJwt"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type SessionRevoker interface {
+	RevokeSessionsByUser(userId int) error
+}
+
+// New signs the user with the id in the path out everywhere.
+func New(log *slog.Logger, sessionRevoker SessionRevoker, tokens *myJwt.Tokens) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.admin.users.sessions.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid user id"))
+			return
+		}
+
+		if err := sessionRevoker.RevokeSessionsByUser(id); err != nil {
+			log.Error("failed to revoke sessions", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		if err := tokens.RevokeUser(id); err != nil {
+			log.Error("failed to revoke access tokens", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		log.Info("user sessions revoked", slog.Int("user_id", id))
+
+		customJson.WriteJson(w, http.StatusOK, resp.OK())
+	}
+}
@@ -0,0 +1,70 @@
+package status
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	"go_url_chortener_api/internal/http-server/middleware/myJwt"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type UserDisabler interface {
+	SetUserDisabled(id int, disabled bool) error
+	RevokeSessionsByUser(userId int) error
+}
+
+// New disables or re-enables the account with the id in the path. A
+// disabled account is signed out everywhere at once.
+func New(log *slog.Logger, userDisabler UserDisabler, tokens *myJwt.Tokens, disabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.admin.users.status.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid user id"))
+			return
+		}
+		if admin, _ := auth.UserFrom(r.Context()); disabled && admin.UserId == id {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("cannot disable your own account"))
+			return
+		}
+
+		err = userDisabler.SetUserDisabled(id, disabled)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			customJson.WriteJson(w, http.StatusNotFound, resp.Error("user not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update user", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		if disabled {
+			if err := userDisabler.RevokeSessionsByUser(id); err != nil {
+				log.Error("failed to revoke sessions", sl.Err(err))
+				customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+				return
+			}
+			if err := tokens.RevokeUser(id); err != nil {
+				log.Error("failed to revoke access tokens", sl.Err(err))
+				customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+				return
+			}
+		}
+		log.Info("user status changed", slog.Int("user_id", id), slog.Bool("disabled", disabled))
+
+		customJson.WriteJson(w, http.StatusOK, resp.OK())
+	}
+}
@@ -0,0 +1,95 @@
+package status
+
+import (
+	"github.com/go-chi/chi/v5"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/middleware/myJwt"
+	"go_url_chortener_api/internal/lib/clock"
+	"go_url_chortener_api/internal/lib/jwtkeys"
+	"go_url_chortener_api/internal/lib/revocation"
+	"go_url_chortener_api/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type memStore struct {
+	disabled map[int]bool
+	signedIn map[int]bool
+}
+
+func (m *memStore) SetUserDisabled(id int, disabled bool) error {
+	if _, ok := m.disabled[id]; !ok {
+		return storage.ErrUserNotFound
+	}
+	m.disabled[id] = disabled
+	return nil
+}
+
+func (m *memStore) RevokeSessionsByUser(userId int) error {
+	m.signedIn[userId] = false
+	return nil
+}
+
+func TestDisableAndEnable(t *testing.T) {
+	keys, err := jwtkeys.Ephemeral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := myJwt.NewTokens(&config.Auth{Issuer: "test", Audience: "test_api"}, keys,
+		revocation.NewMemory(clock.New(), myJwt.TokenLifetime))
+	store := &memStore{disabled: map[int]bool{1: false, 9: false}, signedIn: map[int]bool{9: true}}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			admin := auth.Principal{UserId: 1, Role: domain.RoleAdmin}
+			next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), admin)))
+		})
+	})
+	router.Post("/users/{id}/disable", New(log, store, tokens, true))
+	router.Post("/users/{id}/enable", New(log, store, tokens, false))
+
+	post := func(path string) int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		return rec.Code
+	}
+	authenticate := func(token string) error {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		_, err := tokens.Authenticate(req)
+		return err
+	}
+
+	before, _ := tokens.CreateJWT(&domain.User{Id: 9, Email: "u@example.com"}, 4)
+	if code := post("/users/9/disable"); code != http.StatusOK {
+		t.Fatalf("disable: got %d", code)
+	}
+	if !store.disabled[9] || store.signedIn[9] {
+		t.Errorf("after disable: disabled = %v, signed in = %v", store.disabled[9], store.signedIn[9])
+	}
+	if err := authenticate(before); err == nil {
+		t.Error("access token of the disabled user still accepted")
+	}
+
+	if code := post("/users/9/enable"); code != http.StatusOK || store.disabled[9] {
+		t.Errorf("enable: got %d, disabled = %v", code, store.disabled[9])
+	}
+	after, _ := tokens.CreateJWT(&domain.User{Id: 9, Email: "u@example.com"}, 5)
+	if err := authenticate(after); err != nil {
+		t.Errorf("token issued after enabling: %v", err)
+	}
+
+	if code := post("/users/1/disable"); code != http.StatusBadRequest || store.disabled[1] {
+		t.Errorf("disabling yourself: got %d", code)
+	}
+	if code := post("/users/404/disable"); code != http.StatusNotFound {
+		t.Errorf("unknown user: got %d", code)
+	}
+}
@@ -0,0 +1,99 @@
+package create
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/apikey"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type Request struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=links:read links:write stats:read"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	// Key is only ever shown in this response.
+	Key    string         `json:"key"`
+	APIKey *domain.APIKey `json:"apiKey"`
+}
+
+type APIKeySaver interface {
+	SaveAPIKey(k *domain.APIKey) error
+}
+
+func New(log *slog.Logger, apiKeySaver APIKeySaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.apikeys.create.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		req := new(Request)
+		if err := customJson.DecodeJson(r, req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validationErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.ValidationError(validationErr))
+			return
+		}
+		if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("expiresAt must be in the future"))
+			return
+		}
+
+		user, _ := auth.UserFrom(r.Context())
+
+		key, prefix, err := apikey.Generate()
+		if err != nil {
+			log.Error("failed to generate api key", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		k := &domain.APIKey{
+			UserId:    user.UserId,
+			Name:      req.Name,
+			Prefix:    prefix,
+			Hash:      apikey.Hash(key),
+			Scopes:    req.Scopes,
+			ExpiresAt: req.ExpiresAt,
+		}
+		err = apiKeySaver.SaveAPIKey(k)
+		if errors.Is(err, storage.ErrAPIKeyExists) {
+			// prefix collision, the client can simply retry
+			log.Error("api key prefix taken", slog.String("prefix", prefix))
+			customJson.WriteJson(w, http.StatusConflict, resp.Error("failed to create api key, try again"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to save api key", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		log.Info("api key created", slog.Int("api_key_id", k.Id))
+
+		customJson.WriteJson(w, http.StatusOK, Response{
+			Response: resp.OK(),
+			Key:      key,
+			APIKey:   k,
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package remove
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type APIKeyDeleter interface {
+	DeleteAPIKey(id int, userId int) error
+}
+
+func New(log *slog.Logger, apiKeyDeleter APIKeyDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.apikeys.remove.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid api key id"))
+			return
+		}
+
+		user, _ := auth.UserFrom(r.Context())
+
+		err = apiKeyDeleter.DeleteAPIKey(id, user.UserId)
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			customJson.WriteJson(w, http.StatusNotFound, resp.Error("api key not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to delete api key", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		log.Info("api key revoked", slog.Int("api_key_id", id))
+
+		customJson.WriteJson(w, http.StatusOK, resp.OK())
+	}
+}
@@ -0,0 +1,101 @@
+package email
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	"go_url_chortener_api/internal/http-server/handlers/auth/verify"
+	"go_url_chortener_api/internal/http-server/middleware/myJwt"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/hash"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+)
+
+type Request struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type EmailChanger interface {
+	GetUserById(id int) (*domain.User, error)
+	UpdateEmail(id int, email string, keepSessionId int) error
+}
+
+// New changes the email address of the signed-in user and mails a link to
+// verify the new one. Every other session is ended; the current one gets a
+// new access token carrying the new address.
+func New(log *slog.Logger, emailChanger EmailChanger, hasher hash.PasswordHasher, tokens *myJwt.Tokens, sender *verify.Sender) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.auth.email.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		req := new(Request)
+		if err := customJson.DecodeJson(r, req); err != nil {
+			log.Error("failed to decode json", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("failed to decode request"))
+			return
+		}
+		if err := validator.New().Struct(req); err != nil {
+			validationErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.ValidationError(validationErr))
+			return
+		}
+
+		principal, _ := auth.UserFrom(r.Context())
+		user, err := emailChanger.GetUserById(principal.UserId)
+		if err != nil {
+			log.Error("failed to get user", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		if err := hasher.CheckPassword(user.EncPassword, req.Password); err != nil {
+			log.Info("password does not match", slog.Int("user_id", user.Id))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid password"))
+			return
+		}
+		if req.Email == user.Email {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("email is unchanged"))
+			return
+		}
+
+		err = emailChanger.UpdateEmail(user.Id, req.Email, principal.SessionId)
+		if errors.Is(err, storage.ErrUserExists) {
+			log.Info("email is taken", slog.Int("user_id", user.Id))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("email is already in use"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update email", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		user.Email = req.Email
+		user.EmailVerifiedAt = nil
+
+		jwtToken, err := tokens.RevokeOthers(user, principal.SessionId)
+		if err != nil {
+			log.Error("failed to revoke access tokens", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		if err := sender.Send(r.Context(), user); err != nil {
+			log.Error("failed to send verification email", sl.Err(err))
+		}
+		log.Info("email changed", slog.Int("user_id", user.Id))
+
+		myJwt.SetJWTHeader(w, jwtToken)
+		customJson.WriteJson(w, http.StatusOK, map[string]string{
+			"jwt": jwtToken,
+		})
+	}
+}
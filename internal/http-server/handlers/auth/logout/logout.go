@@ -0,0 +1,122 @@
+package logout
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	"go_url_chortener_api/internal/http-server/handlers/refresh"
+	"go_url_chortener_api/internal/http-server/middleware/myJwt"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+)
+
+type Logouter interface {
+	GetRefresh(hash string) (*refresh.Token, error)
+	RevokeRefreshFamily(familyId string) (int, error)
+}
+
+// New ends the session of the refresh token the client holds and revokes
+// every access token issued to it, as well as to the session of the access
+// token sent along, if any. It works with an expired access token and
+// succeeds when there is nothing to end.
+func New(log *slog.Logger, logouter Logouter, tokens *myJwt.Tokens) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.auth.logout.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		refresh.ClearRefreshCookie(w)
+
+		principal, err := tokens.Authenticate(r)
+		if err == nil {
+			if err := revokeAccess(tokens, principal); err != nil {
+				log.Error("failed to revoke access tokens", sl.Err(err))
+				customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+				return
+			}
+		}
+
+		refToken, err := refresh.GetRefreshCookie(r)
+		if err != nil {
+			customJson.WriteJson(w, http.StatusOK, resp.OK())
+			return
+		}
+
+		token, err := logouter.GetRefresh(refresh.HashToken(refToken))
+		if errors.Is(err, storage.ErrRefreshNotFound) {
+			customJson.WriteJson(w, http.StatusOK, resp.OK())
+			return
+		}
+		if err != nil {
+			log.Error("failed to get refresh token", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		sessionId, err := logouter.RevokeRefreshFamily(token.FamilyId)
+		if err != nil {
+			log.Error("failed to revoke session", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		if sessionId != 0 && sessionId != principal.SessionId {
+			if err := tokens.RevokeSession(sessionId); err != nil {
+				log.Error("failed to revoke access tokens", sl.Err(err))
+				customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+				return
+			}
+		}
+		log.Info("user logged out", slog.Int("user_id", token.UserId))
+
+		customJson.WriteJson(w, http.StatusOK, resp.OK())
+	}
+}
+
+// revokeAccess revokes every access token of the principal's session.
+// Tokens issued before sessions were tracked carry none, so only the
+// presented one can be revoked.
+func revokeAccess(tokens *myJwt.Tokens, principal auth.Principal) error {
+	if principal.SessionId == 0 {
+		return tokens.Revoke(principal)
+	}
+	return tokens.RevokeSession(principal.SessionId)
+}
+
+type AllLogouter interface {
+	RevokeSessionsByUser(userId int) error
+}
+
+// All ends every session of the signed-in user and revokes all access
+// tokens issued to them.
+func All(log *slog.Logger, logouter AllLogouter, tokens *myJwt.Tokens) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.auth.logout.All"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, _ := auth.UserFrom(r.Context())
+
+		if err := logouter.RevokeSessionsByUser(user.UserId); err != nil {
+			log.Error("failed to revoke sessions", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		if err := tokens.RevokeUser(user.UserId); err != nil {
+			log.Error("failed to revoke access tokens", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		log.Info("user logged out everywhere", slog.Int("user_id", user.UserId))
+
+		refresh.ClearRefreshCookie(w)
+		customJson.WriteJson(w, http.StatusOK, resp.OK())
+	}
+}
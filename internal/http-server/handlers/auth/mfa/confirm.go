@@ -0,0 +1,96 @@
+package mfa
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/lib/totp"
+	"log/slog"
+	"net/http"
+)
+
+type ConfirmRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type ConfirmResponse struct {
+	resp.Response
+	// RecoveryCodes are shown once; each signs in a single time without
+	// the authenticator.
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type Confirmer interface {
+	GetUserById(id int) (*domain.User, error)
+	EnableTOTP(userId int, step int64, codeHashes []string) error
+}
+
+// Confirm enables MFA once the user proves their authenticator generates
+// codes from the enrolled secret.
+func Confirm(log *slog.Logger, confirmer Confirmer, codes *totp.TOTP) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.auth.mfa.Confirm"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		req := new(ConfirmRequest)
+		if err := customJson.DecodeJson(r, req); err != nil {
+			log.Error("failed to decode json", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("failed to decode request"))
+			return
+		}
+		if err := validator.New().Struct(req); err != nil {
+			validationErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.ValidationError(validationErr))
+			return
+		}
+
+		principal, _ := auth.UserFrom(r.Context())
+		user, err := confirmer.GetUserById(principal.UserId)
+		if err != nil {
+			log.Error("failed to get user", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		if user.MFAEnabled {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("mfa is already enabled"))
+			return
+		}
+		if user.TOTPSecret == "" {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("mfa enrollment not started"))
+			return
+		}
+
+		step, ok := codes.Verify(user.TOTPSecret, req.Code, user.TOTPLastStep)
+		if !ok {
+			log.Info("invalid totp code", slog.Int("user_id", user.Id))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid code"))
+			return
+		}
+
+		recoveryCodes, hashes, err := newRecoveryCodes()
+		if err != nil {
+			log.Error("failed to generate recovery codes", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		if err := confirmer.EnableTOTP(user.Id, step, hashes); err != nil {
+			log.Error("failed to enable mfa", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		log.Info("mfa enabled", slog.Int("user_id", user.Id))
+
+		customJson.WriteJson(w, http.StatusOK, ConfirmResponse{
+			Response:      resp.OK(),
+			RecoveryCodes: recoveryCodes,
+		})
+	}
+}
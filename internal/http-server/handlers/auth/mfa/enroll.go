@@ -0,0 +1,95 @@
+package mfa
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/hash"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/lib/totp"
+	"log/slog"
+	"net/http"
+)
+
+type EnrollRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+}
+
+type EnrollResponse struct {
+	resp.Response
+	Secret string `json:"secret"`
+	// URI is the otpauth:// URI to show as a QR code.
+	URI string `json:"uri"`
+}
+
+type Enroller interface {
+	GetUserById(id int) (*domain.User, error)
+	SetTOTPSecret(userId int, secret string) error
+}
+
+// Enroll generates a TOTP secret for the signed-in user. The current
+// password is required, so a stolen access token is not enough to tie the
+// account to an attacker's authenticator. The secret takes effect once a
+// code generated from it is sent to Confirm; enrolling again before that
+// replaces the secret. Once MFA is enabled it cannot be enrolled again.
+func Enroll(log *slog.Logger, enroller Enroller, hasher hash.PasswordHasher, issuer string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.auth.mfa.Enroll"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		req := new(EnrollRequest)
+		if err := customJson.DecodeJson(r, req); err != nil {
+			log.Error("failed to decode json", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("failed to decode request"))
+			return
+		}
+		if err := validator.New().Struct(req); err != nil {
+			validationErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.ValidationError(validationErr))
+			return
+		}
+
+		principal, _ := auth.UserFrom(r.Context())
+		user, err := enroller.GetUserById(principal.UserId)
+		if err != nil {
+			log.Error("failed to get user", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		if user.MFAEnabled {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("mfa is already enabled"))
+			return
+		}
+		if err := hasher.CheckPassword(user.EncPassword, req.CurrentPassword); err != nil {
+			log.Info("current password does not match", slog.Int("user_id", user.Id))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid password"))
+			return
+		}
+
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			log.Error("failed to generate totp secret", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		if err := enroller.SetTOTPSecret(user.Id, secret); err != nil {
+			log.Error("failed to save totp secret", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		log.Info("mfa enrollment started", slog.Int("user_id", user.Id))
+
+		customJson.WriteJson(w, http.StatusOK, EnrollResponse{
+			Response: resp.OK(),
+			Secret:   secret,
+			URI:      totp.URI(issuer, user.Email, secret),
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package mfa
+
+import (
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/lib/hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type stubEnroller struct {
+	user   *domain.User
+	secret string
+}
+
+func (s *stubEnroller) GetUserById(int) (*domain.User, error) { return s.user, nil }
+
+func (s *stubEnroller) SetTOTPSecret(_ int, secret string) error {
+	s.secret = secret
+	return nil
+}
+
+func TestEnrollRequiresPassword(t *testing.T) {
+	hasher := hash.NewSHA1Hasher(4)
+	encPassword, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	enroller := &stubEnroller{user: &domain.User{Id: 1, Email: "a@b.c", EncPassword: encPassword}}
+	h := Enroll(slog.New(slog.NewTextHandler(io.Discard, nil)), enroller, hasher, "test")
+
+	do := func(body string) int {
+		r := httptest.NewRequest(http.MethodPost, "/auth/mfa/enroll", strings.NewReader(body))
+		r = r.WithContext(auth.WithUser(r.Context(), auth.Principal{UserId: 1}))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := do(`{}`); code != http.StatusBadRequest {
+		t.Errorf("no password: got %d", code)
+	}
+	if code := do(`{"currentPassword":"wrong"}`); code != http.StatusBadRequest {
+		t.Errorf("wrong password: got %d", code)
+	}
+	if enroller.secret != "" {
+		t.Fatal("secret was set without the password")
+	}
+	if code := do(`{"currentPassword":"correct horse"}`); code != http.StatusOK || enroller.secret == "" {
+		t.Errorf("right password: got %d", code)
+	}
+}
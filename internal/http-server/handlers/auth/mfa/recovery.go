@@ -0,0 +1,38 @@
+package mfa
+
+import (
+	"go_url_chortener_api/internal/lib/onetime"
+	"go_url_chortener_api/internal/lib/random"
+	"strings"
+)
+
+const (
+	recoveryCodeCount = 10
+	// recoveryCodeBytes gives codes of 10 hex characters, shown in two
+	// groups of five.
+	recoveryCodeBytes = 5
+)
+
+// newRecoveryCodes returns the codes shown to the user once and the hashes
+// stored in their place.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := random.NewToken(recoveryCodeBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, HashRecoveryCode(raw))
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode is what recovery codes are stored and looked up by. It
+// ignores case, spaces and dashes, which users tend to get wrong.
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return onetime.Hash(code)
+}
@@ -0,0 +1,106 @@
+package change
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	"go_url_chortener_api/internal/http-server/middleware/myJwt"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/hash"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/lib/password"
+	"log/slog"
+	"net/http"
+)
+
+type Request struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	Password1       string `json:"password1" validate:"required"`
+	Password2       string `json:"password2" validate:"required"`
+}
+
+type PasswordChanger interface {
+	GetUserById(id int) (*domain.User, error)
+	UpdatePassword(id int, encPassword string, keepSessionId int) error
+}
+
+// New changes the password of the signed-in user. Every other session is
+// ended; the current one gets a new access token.
+func New(log *slog.Logger, passwordChanger PasswordChanger, hasher hash.PasswordHasher, tokens *myJwt.Tokens, policy *password.Policy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.auth.password.change.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		req := new(Request)
+		if err := customJson.DecodeJson(r, req); err != nil {
+			log.Error("failed to decode json", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("failed to decode request"))
+			return
+		}
+		if err := validator.New().Struct(req); err != nil {
+			validationErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.ValidationError(validationErr))
+			return
+		}
+
+		principal, _ := auth.UserFrom(r.Context())
+		user, err := passwordChanger.GetUserById(principal.UserId)
+		if err != nil {
+			log.Error("failed to get user", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		if err := hasher.CheckPassword(user.EncPassword, req.CurrentPassword); err != nil {
+			log.Info("current password does not match", slog.Int("user_id", user.Id))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid password"))
+			return
+		}
+		if err := password.Equal(req.Password1, req.Password2); err != nil {
+			log.Error("invalid request", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error(err.Error()))
+			return
+		}
+		violations, err := policy.Check(req.Password1, user.Email)
+		if err != nil {
+			log.Error("failed to check password", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		if len(violations) > 0 {
+			log.Info("password rejected by policy", slog.Int("violations", len(violations)))
+			customJson.WriteJson(w, http.StatusBadRequest, password.ViolationsResponse(violations))
+			return
+		}
+
+		encPassword, err := hasher.Hash(req.Password1)
+		if err != nil {
+			log.Error("failed to hash password", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		if err := passwordChanger.UpdatePassword(user.Id, encPassword, principal.SessionId); err != nil {
+			log.Error("failed to update password", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		jwtToken, err := tokens.RevokeOthers(user, principal.SessionId)
+		if err != nil {
+			log.Error("failed to revoke access tokens", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		log.Info("password changed", slog.Int("user_id", user.Id))
+
+		myJwt.SetJWTHeader(w, jwtToken)
+		customJson.WriteJson(w, http.StatusOK, map[string]string{
+			"jwt": jwtToken,
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package forgot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/clock"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/lib/mailer"
+	"go_url_chortener_api/internal/lib/onetime"
+	"go_url_chortener_api/internal/lib/throttle"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	subject     = "Reset your password"
+	mailTimeout = 30 * time.Second
+)
+
+var errNoBaseURL = errors.New("base url not configured")
+
+type Request struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type Forgetter interface {
+	GetUser(email string) (*domain.User, error)
+	SavePasswordReset(userId int, hash string, expiresAt time.Time) error
+}
+
+// NewCooldown returns the throttle that keeps an address from getting more
+// than one reset mail per cooldown. Each mail sent counts as a failure that
+// blocks the address until the cooldown has passed.
+func NewCooldown(clk clock.Clock, cooldown time.Duration) *throttle.Throttle {
+	return throttle.New(clk, throttle.Policy{
+		BaseDelay:  cooldown,
+		MaxDelay:   cooldown,
+		ResetAfter: cooldown,
+	})
+}
+
+// New mails a password reset link to the address if it belongs to an
+// account. It answers 200 either way, and sends the mail in the background
+// so the response time does not tell either. While the address is in its
+// cooldown, nothing is sent.
+func New(log *slog.Logger, forgetter Forgetter, m mailer.Mailer, cooldown *throttle.Throttle, authCfg *config.Auth, httpCfg *config.HttpServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.auth.password.forgot.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		req := new(Request)
+		if err := customJson.DecodeJson(r, req); err != nil {
+			log.Error("failed to decode json", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("failed to decode request"))
+			return
+		}
+		if err := validator.New().Struct(req); err != nil {
+			validationErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.ValidationError(validationErr))
+			return
+		}
+
+		// The address is throttled whether or not it has an account, so
+		// the cooldown does not tell either.
+		address := strings.ToLower(strings.TrimSpace(req.Email))
+		if cooldown.Wait(address) > 0 {
+			log.Info("password reset email skipped, address in cooldown")
+			customJson.WriteJson(w, http.StatusOK, resp.OK())
+			return
+		}
+		cooldown.Fail(address)
+
+		user, err := forgetter.GetUser(req.Email)
+		switch {
+		case errors.Is(err, storage.ErrUserNotFound):
+			log.Info("no account for password reset")
+		case err != nil:
+			log.Error("failed to get user", sl.Err(err))
+		case user.Disabled:
+			log.Info("password reset for disabled user", slog.Int("user_id", user.Id))
+		default:
+			msg, err := resetMessage(forgetter, user, authCfg, httpCfg)
+			if err != nil {
+				log.Error("failed to create password reset", sl.Err(err))
+				break
+			}
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+				defer cancel()
+				if err := m.Send(ctx, msg); err != nil {
+					log.Error("failed to send password reset email", sl.Err(err))
+					return
+				}
+				log.Info("password reset email sent", slog.Int("user_id", user.Id))
+			}()
+		}
+
+		customJson.WriteJson(w, http.StatusOK, resp.OK())
+	}
+}
+
+// resetMessage stores a new reset token for the user and returns the mail
+// carrying it. The link is built from the configured base URL only: taken
+// from the request, a forged Host header would send the token elsewhere.
+func resetMessage(forgetter Forgetter, user *domain.User, authCfg *config.Auth, httpCfg *config.HttpServer) (mailer.Message, error) {
+	if httpCfg.BaseURL == "" {
+		return mailer.Message{}, errNoBaseURL
+	}
+	token, hash, err := onetime.New()
+	if err != nil {
+		return mailer.Message{}, err
+	}
+	if err := forgetter.SavePasswordReset(user.Id, hash, time.Now().Add(authCfg.PasswordResetLifetime)); err != nil {
+		return mailer.Message{}, err
+	}
+
+	link := httpCfg.BaseURL + "/auth/password/reset?token=" + url.QueryEscape(token)
+
+	return mailer.Message{
+		To:      user.Email,
+		Subject: subject,
+		Body: fmt.Sprintf("Someone asked to reset the password of your account. "+
+			"Open this link to choose a new one:\n\n%s\n\n"+
+			"The link can be used once and expires in %s. If it was not you, ignore this email.\n",
+			link, authCfg.PasswordResetLifetime),
+	}, nil
+}
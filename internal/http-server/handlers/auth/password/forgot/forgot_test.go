@@ -0,0 +1,83 @@
+package forgot
+
+import (
+	"context"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/lib/mailer"
+	"go_url_chortener_api/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+type memStore struct{ users map[string]*domain.User }
+
+func (m *memStore) GetUser(email string) (*domain.User, error) {
+	u, ok := m.users[email]
+	if !ok {
+		return nil, storage.ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (m *memStore) SavePasswordReset(int, string, time.Time) error { return nil }
+
+type chanMailer chan mailer.Message
+
+func (c chanMailer) Send(_ context.Context, msg mailer.Message) error {
+	c <- msg
+	return nil
+}
+
+func TestCooldown(t *testing.T) {
+	store := &memStore{users: map[string]*domain.User{"a@b.c": {Id: 1, Email: "a@b.c"}}}
+	sent := make(chanMailer, 10)
+	clk := &fakeClock{now: time.Unix(1700000000, 0)}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := New(log, store, sent, NewCooldown(clk, 5*time.Minute),
+		&config.Auth{PasswordResetLifetime: time.Hour}, &config.HttpServer{BaseURL: "https://sho.rt"})
+
+	post := func(email string) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/password/forgot",
+			strings.NewReader(`{"email":"`+email+`"}`)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d", rec.Code)
+		}
+	}
+	received := func() int {
+		n := 0
+		for {
+			select {
+			case msg := <-sent:
+				if !strings.Contains(msg.Body, "https://sho.rt/auth/password/reset?token=") {
+					t.Errorf("unexpected link in %q", msg.Body)
+				}
+				n++
+			case <-time.After(100 * time.Millisecond):
+				return n
+			}
+		}
+	}
+
+	post("a@b.c")
+	post("A@b.c")
+	if n := received(); n != 1 {
+		t.Fatalf("got %d mails within the cooldown, want 1", n)
+	}
+
+	clk.now = clk.now.Add(5*time.Minute + time.Second)
+	post("a@b.c")
+	if n := received(); n != 1 {
+		t.Errorf("got %d mails after the cooldown, want 1", n)
+	}
+}
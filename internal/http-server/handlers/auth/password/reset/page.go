@@ -0,0 +1,37 @@
+package reset
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/http-server/customJson"
+	"go_url_chortener_api/internal/http-server/view"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"log/slog"
+	"net/http"
+)
+
+// Page serves the form the emailed reset link opens. The form posts the
+// token and the new password to New. The token is in the URL, so the page
+// is not cached and sends no referrer.
+func Page(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.auth.password.reset.Page"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid or expired token"))
+			return
+		}
+		if err := view.Render(w, http.StatusOK, "reset.html", map[string]any{"Token": token}); err != nil {
+			log.Error("failed to render page", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+		}
+	}
+}
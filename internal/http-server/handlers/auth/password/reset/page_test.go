@@ -0,0 +1,32 @@
+package reset
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPage(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	rec := httptest.NewRecorder()
+	Page(log).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/password/reset?token=abc%22def", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `name="token" value="abc&#34;def"`) {
+		t.Errorf("token not in the form:\n%s", body)
+	}
+	if got := rec.Header().Get("Referrer-Policy"); got != "no-referrer" {
+		t.Errorf("Referrer-Policy = %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	Page(log).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/password/reset", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("without token: status = %d", rec.Code)
+	}
+}
@@ -0,0 +1,113 @@
+package reset
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/customJson"
+	"go_url_chortener_api/internal/http-server/middleware/myJwt"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/hash"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/lib/onetime"
+	"go_url_chortener_api/internal/lib/password"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+)
+
+type Request struct {
+	Token     string `json:"token" validate:"required"`
+	Password1 string `json:"password1" validate:"required"`
+	Password2 string `json:"password2" validate:"required"`
+}
+
+type PasswordResetter interface {
+	GetUserByPasswordReset(hash string) (*domain.User, error)
+	ResetPassword(hash string, encPassword string) (int, error)
+}
+
+// New sets a new password with a token mailed by forgot.New and signs the
+// user out everywhere.
+func New(log *slog.Logger, passwordResetter PasswordResetter, hasher hash.PasswordHasher, tokens *myJwt.Tokens, policy *password.Policy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.auth.password.reset.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		req := new(Request)
+		if err := customJson.DecodeJson(r, req); err != nil {
+			log.Error("failed to decode json", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("failed to decode request"))
+			return
+		}
+		if err := validator.New().Struct(req); err != nil {
+			validationErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.ValidationError(validationErr))
+			return
+		}
+		if err := password.Equal(req.Password1, req.Password2); err != nil {
+			log.Error("invalid request", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error(err.Error()))
+			return
+		}
+		// The token is only spent below; looking it up first gives the
+		// email the password is checked against.
+		user, err := passwordResetter.GetUserByPasswordReset(onetime.Hash(req.Token))
+		if errors.Is(err, storage.ErrPasswordResetNotFound) {
+			log.Info("invalid password reset token")
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid or expired token"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get password reset", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		violations, err := policy.Check(req.Password1, user.Email)
+		if err != nil {
+			log.Error("failed to check password", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		if len(violations) > 0 {
+			log.Info("password rejected by policy", slog.Int("violations", len(violations)))
+			customJson.WriteJson(w, http.StatusBadRequest, password.ViolationsResponse(violations))
+			return
+		}
+
+		encPassword, err := hasher.Hash(req.Password1)
+		if err != nil {
+			log.Error("failed to hash password", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		userId, err := passwordResetter.ResetPassword(onetime.Hash(req.Token), encPassword)
+		if errors.Is(err, storage.ErrPasswordResetNotFound) {
+			log.Info("invalid password reset token")
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid or expired token"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to reset password", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		// Refresh tokens went with the sessions; access tokens still valid
+		// for a few minutes are cut off too.
+		if err := tokens.RevokeUser(userId); err != nil {
+			log.Error("failed to revoke access tokens", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		log.Info("password reset", slog.Int("user_id", userId))
+
+		customJson.WriteJson(w, http.StatusOK, resp.OK())
+	}
+}
@@ -0,0 +1,52 @@
+package signin
+
+import (
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"go_url_chortener_api/internal/lib/jwtkeys"
+	"strconv"
+	"time"
+)
+
+// challengeAudience keeps challenges from being accepted as any other
+// token signed with the same keys.
+const challengeAudience = "mfa"
+
+const challengeLifetime = 5 * time.Minute
+
+var errInvalidChallenge = errors.New("invalid mfa challenge claims")
+
+// challengeClaims prove that the password step of the sign-in succeeded.
+type challengeClaims struct {
+	UserId int `json:"userId"`
+	jwt.RegisteredClaims
+}
+
+func createChallenge(keys *jwtkeys.Manager, userId int) (string, error) {
+	now := time.Now()
+	return keys.Sign(&challengeClaims{
+		UserId: userId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(userId),
+			Audience:  jwt.ClaimStrings{challengeAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(challengeLifetime)),
+		},
+	})
+}
+
+func parseChallenge(keys *jwtkeys.Manager, token string) (*challengeClaims, error) {
+	claims := new(challengeClaims)
+	_, err := jwt.ParseWithClaims(token, claims, keys.Keyfunc,
+		jwt.WithValidMethods(keys.Methods()),
+		jwt.WithAudience(challengeAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if claims.UserId <= 0 || claims.Subject != strconv.Itoa(claims.UserId) {
+		return nil, errInvalidChallenge
+	}
+	return claims, nil
+}
@@ -0,0 +1,71 @@
+package signin
+
+import (
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/clock"
+	"go_url_chortener_api/internal/lib/throttle"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CodeTooManyAttempts is returned while sign-ins of the account or from
+// the client are throttled; Retry-After tells for how long.
+const CodeTooManyAttempts = "too_many_attempts"
+
+// Limits throttle failed sign-ins per account and per client IP. Failed
+// MFA codes count against both too.
+type Limits struct {
+	Account *throttle.Throttle
+	IP      *throttle.Throttle
+}
+
+func NewLimits(clk clock.Clock, cfg *config.SignInThrottle) *Limits {
+	return &Limits{
+		Account: throttle.New(clk, throttle.Policy{
+			Threshold:        cfg.AccountThreshold,
+			BaseDelay:        cfg.BaseDelay,
+			MaxDelay:         cfg.MaxDelay,
+			LockoutThreshold: cfg.LockoutThreshold,
+			LockoutDuration:  cfg.LockoutDuration,
+			ResetAfter:       cfg.ResetAfter,
+		}),
+		// An IP is only slowed down, never locked out: many users can
+		// share one behind a NAT.
+		IP: throttle.New(clk, throttle.Policy{
+			Threshold:  cfg.IPThreshold,
+			BaseDelay:  cfg.BaseDelay,
+			MaxDelay:   cfg.MaxDelay,
+			ResetAfter: cfg.ResetAfter,
+		}),
+	}
+}
+
+// accountKey counts attempts on an email whether or not it has an account,
+// so throttling does not tell which emails exist.
+func accountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (l *Limits) wait(account, ip string) time.Duration {
+	wait := l.Account.Wait(account)
+	if ipWait := l.IP.Wait(ip); ipWait > wait {
+		wait = ipWait
+	}
+	return wait
+}
+
+func (l *Limits) fail(account, ip string) {
+	l.Account.Fail(account)
+	l.IP.Fail(ip)
+}
+
+func tooManyAttempts(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	customJson.WriteJson(w, http.StatusTooManyRequests,
+		resp.ErrorCode(CodeTooManyAttempts, "too many failed attempts, try again later"))
+}
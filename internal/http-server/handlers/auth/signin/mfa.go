@@ -0,0 +1,128 @@
+package signin
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/customJson"
+	"go_url_chortener_api/internal/http-server/handlers/auth/mfa"
+	"go_url_chortener_api/internal/http-server/handlers/refresh"
+	"go_url_chortener_api/internal/http-server/middleware/myJwt"
+	"go_url_chortener_api/internal/lib/api/request"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/jwtkeys"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/lib/totp"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+)
+
+type MFARequest struct {
+	Challenge string `json:"challenge" validate:"required"`
+	// Code is a TOTP code or one of the recovery codes.
+	Code string `json:"code" validate:"required"`
+}
+
+type MFASignInner interface {
+	GetUserById(id int) (*domain.User, error)
+	UseTOTPStep(userId int, step int64) error
+	UseRecoveryCode(userId int, hash string) error
+	SaveSession(session *domain.Session) error
+	SaveRefresh(token *refresh.Token) error
+}
+
+// MFA completes a sign-in New answered with a challenge.
+func MFA(log *slog.Logger, signInner MFASignInner, codes *totp.TOTP, tokens *myJwt.Tokens, keys *jwtkeys.Manager, cfg *config.Auth, limits *Limits) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.auth.signin.MFA"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		req := new(MFARequest)
+		if err := customJson.DecodeJson(r, req); err != nil {
+			log.Error("failed to decode json", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("bad request"))
+			return
+		}
+		if err := validator.New().Struct(req); err != nil {
+			validationErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.ValidationError(validationErr))
+			return
+		}
+
+		claims, err := parseChallenge(keys, req.Challenge)
+		if err != nil {
+			log.Info("invalid mfa challenge", sl.Err(err))
+			customJson.WriteJson(w, http.StatusUnauthorized, resp.Error("invalid or expired challenge"))
+			return
+		}
+
+		user, err := signInner.GetUserById(claims.UserId)
+		if err != nil {
+			log.Error("failed to get user", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		// the account may have changed since the password step
+		if !allowed(log, w, user, cfg) {
+			return
+		}
+		if !user.MFAEnabled {
+			customJson.WriteJson(w, http.StatusUnauthorized, resp.Error("invalid or expired challenge"))
+			return
+		}
+
+		account, ip := accountKey(user.Email), request.ClientIP(r)
+		if wait := limits.wait(account, ip); wait > 0 {
+			log.Info("mfa throttled", slog.Int("user_id", user.Id), slog.Duration("wait", wait))
+			tooManyAttempts(w, wait)
+			return
+		}
+
+		err = checkCode(signInner, codes, user, req.Code)
+		if errors.Is(err, errInvalidCode) {
+			log.Info("invalid mfa code", slog.Int("user_id", user.Id), slog.String("ip", ip))
+			limits.fail(account, ip)
+			customJson.WriteJson(w, http.StatusUnauthorized, resp.Error("invalid code"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to check mfa code", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		limits.Account.Reset(account)
+
+		if err := startSession(w, r, signInner, tokens, keys, user); err != nil {
+			log.Error("failed to start session", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+	}
+}
+
+var errInvalidCode = errors.New("invalid mfa code")
+
+// checkCode accepts a TOTP code that was not used before, or else an
+// unused recovery code, which is spent.
+func checkCode(signInner MFASignInner, codes *totp.TOTP, user *domain.User, code string) error {
+	if step, ok := codes.Verify(user.TOTPSecret, code, user.TOTPLastStep); ok {
+		err := signInner.UseTOTPStep(user.Id, step)
+		if errors.Is(err, storage.ErrTOTPCodeUsed) {
+			return errInvalidCode
+		}
+		return err
+	}
+
+	err := signInner.UseRecoveryCode(user.Id, mfa.HashRecoveryCode(code))
+	if errors.Is(err, storage.ErrRecoveryCodeNotFound) {
+		return errInvalidCode
+	}
+	return err
+}
@@ -0,0 +1,152 @@
+package signin
+
+import (
+	"encoding/json"
+	"errors"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/handlers/auth/mfa"
+	"go_url_chortener_api/internal/http-server/handlers/refresh"
+	"go_url_chortener_api/internal/http-server/middleware/myJwt"
+	"go_url_chortener_api/internal/lib/clock"
+	"go_url_chortener_api/internal/lib/jwtkeys"
+	"go_url_chortener_api/internal/lib/revocation"
+	"go_url_chortener_api/internal/lib/totp"
+	"go_url_chortener_api/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+// plainHasher compares passwords as they are.
+type plainHasher struct{}
+
+func (plainHasher) Hash(p string) (string, error) { return p, nil }
+
+func (plainHasher) NeedsRehash(string) bool { return false }
+
+func (plainHasher) CheckPassword(hash string, p string) error {
+	if hash != p {
+		return errors.New("mismatch")
+	}
+	return nil
+}
+
+type memStore struct {
+	user     *domain.User
+	recovery map[string]bool
+	sessions int
+}
+
+func (m *memStore) GetUser(email string) (*domain.User, error) {
+	if email != m.user.Email {
+		return nil, storage.ErrUserNotFound
+	}
+	return m.user, nil
+}
+
+func (m *memStore) GetUserById(int) (*domain.User, error) { return m.user, nil }
+
+func (m *memStore) UpdatePasswordHash(_ int, encPassword string) error {
+	m.user.EncPassword = encPassword
+	return nil
+}
+
+func (m *memStore) UseTOTPStep(_ int, step int64) error {
+	if step <= m.user.TOTPLastStep {
+		return storage.ErrTOTPCodeUsed
+	}
+	m.user.TOTPLastStep = step
+	return nil
+}
+
+func (m *memStore) UseRecoveryCode(_ int, hash string) error {
+	if !m.recovery[hash] {
+		return storage.ErrRecoveryCodeNotFound
+	}
+	delete(m.recovery, hash)
+	return nil
+}
+
+func (m *memStore) SaveSession(s *domain.Session) error {
+	m.sessions++
+	s.Id = m.sessions
+	return nil
+}
+
+func (m *memStore) SaveRefresh(*refresh.Token) error { return nil }
+
+func post(h http.HandlerFunc, body string) (int, map[string]string) {
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	got := map[string]string{}
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	return w.Code, got
+}
+
+func setup(t *testing.T) (*slog.Logger, *config.Auth, *jwtkeys.Manager, *myJwt.Tokens) {
+	t.Helper()
+	keys, err := jwtkeys.Ephemeral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Auth{Issuer: "test", Audience: "test_api"}
+	tokens := myJwt.NewTokens(cfg, keys, revocation.NewMemory(clock.New(), myJwt.TokenLifetime))
+	return slog.New(slog.NewTextHandler(io.Discard, nil)), cfg, keys, tokens
+}
+
+func TestMFA(t *testing.T) {
+	log, cfg, keys, tokens := setup(t)
+
+	secret, _ := totp.GenerateSecret()
+	store := &memStore{
+		user: &domain.User{Id: 7, Email: "a@b.c", EncPassword: "pw", MFAEnabled: true, TOTPSecret: secret},
+		recovery: map[string]bool{
+			mfa.HashRecoveryCode("abcde12345"): true,
+		},
+	}
+	clk := &fakeClock{now: time.Unix(1700000000, 0)}
+	limits := NewLimits(clk, &config.SignInThrottle{AccountThreshold: 10, IPThreshold: 10})
+	signIn, err := New(log, store, plainHasher{}, tokens, keys, cfg, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	complete := MFA(log, store, totp.New(clk), tokens, keys, cfg, limits)
+
+	code, body := post(signIn, `{"email":"a@b.c","password":"pw"}`)
+	if code != http.StatusOK || body["code"] != CodeMFARequired || body["jwt"] != "" {
+		t.Fatalf("password step: got %d %v", code, body)
+	}
+	challenge := body["challenge"]
+
+	totpCode, _ := totp.CodeAt(secret, clk.now)
+	code, body = post(complete, `{"challenge":"`+challenge+`","code":"`+totpCode+`"}`)
+	if code != http.StatusOK || body["jwt"] == "" {
+		t.Fatalf("totp code: got %d %v", code, body)
+	}
+
+	tests := []struct {
+		name, challenge, code string
+		want                  int
+	}{
+		{"replayed code", challenge, totpCode, http.StatusUnauthorized},
+		{"wrong code", challenge, "000000", http.StatusUnauthorized},
+		{"forged challenge", "x.y.z", totpCode, http.StatusUnauthorized},
+		{"recovery code", challenge, "ABCDE-12345", http.StatusOK},
+		{"spent recovery code", challenge, "abcde-12345", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		code, body := post(complete, `{"challenge":"`+tt.challenge+`","code":"`+tt.code+`"}`)
+		if code != tt.want {
+			t.Errorf("%s: got %d %v, want %d", tt.name, code, body, tt.want)
+		}
+	}
+}
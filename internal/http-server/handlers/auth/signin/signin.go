@@ -1,15 +1,22 @@
 package signin
 
 import (
+	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-playground/validator/v10"
+	"go_url_chortener_api/internal/config"
 	"go_url_chortener_api/internal/domain"
 	"go_url_chortener_api/internal/http-server/customJson"
 	"go_url_chortener_api/internal/http-server/handlers/refresh"
 	"go_url_chortener_api/internal/http-server/middleware/myJwt"
+	"go_url_chortener_api/internal/http-server/middleware/verified"
+	"go_url_chortener_api/internal/lib/api/request"
 	resp "go_url_chortener_api/internal/lib/api/response"
 	"go_url_chortener_api/internal/lib/hash"
+	"go_url_chortener_api/internal/lib/jwtkeys"
 	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
 	"log/slog"
 	"net/http"
 )
@@ -19,16 +26,43 @@ type Request struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// CodePasswordResetRequired is returned when an administrator has required
+// the user to reset their password before signing in again.
+const CodePasswordResetRequired = "password_reset_required"
+
+// CodeMFARequired tells the client to complete the sign-in with a second
+// factor, see MFA.
+const CodeMFARequired = "mfa_required"
+
+type ChallengeResponse struct {
+	resp.Response
+	Challenge string `json:"challenge"`
+}
+
 type SignInner interface {
 	GetUser(email string) (*domain.User, error)
-	SaveRefresh(string, int) error
-	DeleteRefreshByUserId(int) error
+	UpdatePasswordHash(id int, encPassword string) error
+	SaveSession(session *domain.Session) error
+	SaveRefresh(token *refresh.Token) error
 }
 
-func New(log *slog.Logger, signInner SignInner, hasher hash.PasswordHasher) http.HandlerFunc {
+// dummyPassword is hashed once so that unknown emails take as long to
+// refuse as wrong passwords.
+const dummyPassword = "dummy password for timing"
+
+// New returns the sign-in handler. It fails when the dummy password cannot
+// be hashed, as unknown emails would then be refused faster than wrong
+// passwords.
+func New(log *slog.Logger, signInner SignInner, hasher hash.PasswordHasher, tokens *myJwt.Tokens, keys *jwtkeys.Manager, cfg *config.Auth, limits *Limits) (http.HandlerFunc, error) {
+	const fn = "handlers.auth.signin.New"
+	dummyHash, err := hasher.Hash(dummyPassword)
+	if err != nil {
+		return nil, fmt.Errorf("%s : failed to hash dummy password: %w", fn, err)
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const fn = "handlers.auth.signin.New"
-		log.With(
+		log := log.With(
 			slog.String("fn", fn),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
@@ -38,6 +72,7 @@ func New(log *slog.Logger, signInner SignInner, hasher hash.PasswordHasher) http
 		if err := customJson.DecodeJson(r, req); err != nil {
 			log.Error("failed to decode json", sl.Err(err))
 			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("bad request"))
+			return
 		}
 
 		if err := validator.New().Struct(req); err != nil {
@@ -47,53 +82,153 @@ func New(log *slog.Logger, signInner SignInner, hasher hash.PasswordHasher) http
 			return
 		}
 
+		account, ip := accountKey(req.Email), request.ClientIP(r)
+		if wait := limits.wait(account, ip); wait > 0 {
+			log.Info("sign-in throttled", slog.String("ip", ip), slog.Duration("wait", wait))
+			tooManyAttempts(w, wait)
+			return
+		}
+
 		user, err := signInner.GetUser(req.Email)
+		if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+			log.Error("failed to get user", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
 		if err != nil {
-			log.Error("failed to find user", sl.Err(err))
-			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("user with this email not found"))
+			_ = hasher.CheckPassword(dummyHash, req.Password)
+			log.Info("sign-in with unknown email", slog.String("ip", ip))
+			limits.fail(account, ip)
+			invalidCredentials(w)
 			return
 		}
 		if err := hasher.CheckPassword(user.EncPassword, req.Password); err != nil {
-			log.Error("failed to match password", sl.Err(err))
-			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid password"))
+			log.Info("sign-in with wrong password", slog.Int("user_id", user.Id), slog.String("ip", ip))
+			limits.fail(account, ip)
+			invalidCredentials(w)
 			return
 		}
-
-		jwtToken, err := myJwt.CreateJWT(user.Id, user.Email)
-
-		if err != nil {
-			log.Error("failed to create JWT token", sl.Err(err))
-			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+		rehash(log, signInner, hasher, user, req.Password)
+		if !allowed(log, w, user, cfg) {
 			return
 		}
 
-		refreshToken, err := refresh.CreateRefresh(user.Id)
-		if err != nil {
-			log.Error("failed to create refresh token", sl.Err(err))
-			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+		// The password is only the first step for users with MFA: they get
+		// a challenge to complete at /auth/signin/mfa.
+		if user.MFAEnabled {
+			challenge, err := createChallenge(keys, user.Id)
+			if err != nil {
+				log.Error("failed to create mfa challenge", sl.Err(err))
+				customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+				return
+			}
+			log.Info("mfa required", slog.Int("user_id", user.Id))
+			customJson.WriteJson(w, http.StatusOK, ChallengeResponse{
+				Response:  resp.Response{Status: resp.StatusOK, Code: CodeMFARequired},
+				Challenge: challenge,
+			})
 			return
 		}
 
-		if err := signInner.DeleteRefreshByUserId(user.Id); err != nil {
-			log.Error("")
+		// With MFA the failures are only reset once the second factor
+		// is through, or re-entering the password would reset the count
+		// of guessed codes.
+		limits.Account.Reset(account)
+		if err := startSession(w, r, signInner, tokens, keys, user); err != nil {
+			log.Error("failed to start session", sl.Err(err))
 			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
 			return
 		}
-		log.Info("old refresh token was deleted")
+	}, nil
+}
 
-		if err := signInner.SaveRefresh(refreshToken, user.Id); err != nil {
-			log.Error("failed to create refresh token", sl.Err(err))
-			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
-			return
-		}
+// rehash upgrades the stored hash to the configured format while the
+// password is at hand. A failure only means trying again next time.
+func rehash(log *slog.Logger, signInner SignInner, hasher hash.PasswordHasher, user *domain.User, password string) {
+	if !hasher.NeedsRehash(user.EncPassword) {
+		return
+	}
+	encPassword, err := hasher.Hash(password)
+	if err != nil {
+		log.Error("failed to rehash password", sl.Err(err))
+		return
+	}
+	if err := signInner.UpdatePasswordHash(user.Id, encPassword); err != nil {
+		log.Error("failed to save rehashed password", sl.Err(err))
+		return
+	}
+	user.EncPassword = encPassword
+	log.Info("password rehashed", slog.Int("user_id", user.Id))
+}
+
+// invalidCredentials is the same for unknown emails and wrong passwords.
+func invalidCredentials(w http.ResponseWriter) {
+	customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid email or password"))
+}
+
+// allowed refuses accounts that may not sign in right now.
+func allowed(log *slog.Logger, w http.ResponseWriter, user *domain.User, cfg *config.Auth) bool {
+	if user.Disabled {
+		log.Info("user is disabled", slog.Int("user_id", user.Id))
+		customJson.WriteJson(w, http.StatusForbidden, resp.Error("account is disabled"))
+		return false
+	}
+	if user.PasswordResetRequired {
+		log.Info("user must reset password", slog.Int("user_id", user.Id))
+		customJson.WriteJson(w, http.StatusForbidden, resp.ErrorCode(CodePasswordResetRequired, "password reset required"))
+		return false
+	}
+	if cfg.RequireVerifiedEmail && !user.EmailVerified() {
+		log.Info("user email is not verified", slog.Int("user_id", user.Id))
+		customJson.WriteJson(w, http.StatusForbidden, resp.ErrorCode(verified.CodeEmailNotVerified, "email address is not verified"))
+		return false
+	}
+	return true
+}
 
-		myJwt.SetJWTHeader(w, jwtToken)
-		myJwt.SetIdCookie(w, user.Id)
+type sessionSaver interface {
+	SaveSession(session *domain.Session) error
+	SaveRefresh(token *refresh.Token) error
+}
 
-		refresh.SetRefreshCookie(w, refreshToken)
+// startSession signs the user in: it opens a session and answers with its
+// access token and refresh cookie.
+func startSession(w http.ResponseWriter, r *http.Request, saver sessionSaver, tokens *myJwt.Tokens, keys *jwtkeys.Manager, user *domain.User) error {
+	// Every sign-in starts its own session with its own token family,
+	// so signing in on one device does not log out the others.
+	familyId, err := refresh.NewFamily()
+	if err != nil {
+		return fmt.Errorf("failed to create token family: %w", err)
+	}
+	session := &domain.Session{
+		UserId:    user.Id,
+		FamilyId:  familyId,
+		UserAgent: r.UserAgent(),
+		IP:        request.ClientIP(r),
+	}
+	if err := saver.SaveSession(session); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
 
-		customJson.WriteJson(w, http.StatusOK, map[string]string{
-			"jwt": jwtToken,
-		})
+	refreshToken, stored, err := refresh.CreateRefresh(keys, user.Id, familyId)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	if err := saver.SaveRefresh(stored); err != nil {
+		return fmt.Errorf("failed to save refresh token: %w", err)
 	}
+
+	jwtToken, err := tokens.CreateJWT(user, session.Id)
+	if err != nil {
+		return fmt.Errorf("failed to create JWT token: %w", err)
+	}
+
+	myJwt.SetJWTHeader(w, jwtToken)
+
+	refresh.SetRefreshCookie(w, refreshToken)
+
+	customJson.WriteJson(w, http.StatusOK, map[string]string{
+		"jwt": jwtToken,
+	})
+	return nil
 }
@@ -0,0 +1,91 @@
+package signin
+
+import (
+	"errors"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/lib/hash"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	log, cfg, keys, tokens := setup(t)
+	store := &memStore{user: &domain.User{Id: 7, Email: "a@b.c", EncPassword: "pw"}}
+	clk := &fakeClock{now: time.Unix(1700000000, 0)}
+	limits := NewLimits(clk, &config.SignInThrottle{
+		AccountThreshold: 2,
+		IPThreshold:      100,
+		BaseDelay:        time.Minute,
+		MaxDelay:         time.Hour,
+		ResetAfter:       time.Hour,
+	})
+	signIn, err := New(log, store, plainHasher{}, tokens, keys, cfg, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// unknown emails and wrong passwords are refused alike
+	_, unknown := post(signIn, `{"email":"x@y.z","password":"pw"}`)
+	code, wrong := post(signIn, `{"email":"a@b.c","password":"guess"}`)
+	if code != http.StatusBadRequest || unknown["error"] != wrong["error"] {
+		t.Fatalf("got %d %v and %v", code, unknown, wrong)
+	}
+
+	post(signIn, `{"email":"A@b.c","password":"guess"}`)
+	post(signIn, `{"email":"a@b.c","password":"guess"}`)
+	if code, body := post(signIn, `{"email":"a@b.c","password":"pw"}`); code != http.StatusTooManyRequests ||
+		body["code"] != CodeTooManyAttempts {
+		t.Fatalf("throttled account: got %d %v", code, body)
+	}
+	if code, _ := post(signIn, `{"email":"x@y.z","password":"pw"}`); code != http.StatusBadRequest {
+		t.Errorf("other account: got %d", code)
+	}
+
+	clk.now = clk.now.Add(time.Minute)
+	if code, body := post(signIn, `{"email":"a@b.c","password":"pw"}`); code != http.StatusOK || body["jwt"] == "" {
+		t.Fatalf("after backoff: got %d %v", code, body)
+	}
+	// a success resets the count
+	post(signIn, `{"email":"a@b.c","password":"guess"}`)
+	if code, _ := post(signIn, `{"email":"a@b.c","password":"pw"}`); code != http.StatusOK {
+		t.Errorf("after reset: got %d", code)
+	}
+}
+
+func TestRehash(t *testing.T) {
+	log, cfg, keys, tokens := setup(t)
+	old, _ := hash.NewSHA1Hasher(4).Hash("pw")
+	store := &memStore{user: &domain.User{Id: 7, Email: "a@b.c", EncPassword: old}}
+	argon := hash.NewArgon2Hasher(hash.Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1})
+	clk := &fakeClock{now: time.Unix(1700000000, 0)}
+	signIn, err := New(log, store, argon, tokens, keys, cfg, NewLimits(clk, &config.SignInThrottle{AccountThreshold: 10, IPThreshold: 10}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code, body := post(signIn, `{"email":"a@b.c","password":"pw"}`); code != http.StatusOK {
+		t.Fatalf("got %d %v", code, body)
+	}
+	if store.user.EncPassword == old || argon.NeedsRehash(store.user.EncPassword) {
+		t.Fatalf("hash not upgraded: %s", store.user.EncPassword)
+	}
+	if code, _ := post(signIn, `{"email":"a@b.c","password":"pw"}`); code != http.StatusOK {
+		t.Errorf("sign-in with the new hash: got %d", code)
+	}
+}
+
+// brokenHasher cannot hash, so no dummy hash can be made.
+type brokenHasher struct{ plainHasher }
+
+func (brokenHasher) Hash(string) (string, error) { return "", errors.New("no entropy") }
+
+func TestNewWithoutDummyHash(t *testing.T) {
+	log, cfg, keys, tokens := setup(t)
+	clk := &fakeClock{now: time.Unix(1700000000, 0)}
+	limits := NewLimits(clk, &config.SignInThrottle{AccountThreshold: 10, IPThreshold: 10})
+	if _, err := New(log, &memStore{}, brokenHasher{}, tokens, keys, cfg, limits); err == nil {
+		t.Fatal("handler was built without a dummy hash")
+	}
+}
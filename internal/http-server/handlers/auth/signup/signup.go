@@ -1,34 +1,22 @@
 package signup
 
 import (
-	"errors"
-	"fmt"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-playground/validator/v10"
 	"go_url_chortener_api/internal/domain"
 	"go_url_chortener_api/internal/http-server/customJson"
+	"go_url_chortener_api/internal/http-server/handlers/auth/verify"
 	resp "go_url_chortener_api/internal/lib/api/response"
 	"go_url_chortener_api/internal/lib/hash"
 	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/lib/password"
 	"log/slog"
 	"net/http"
-	"unicode"
-)
-
-const passwordLength = 8
-
-var (
-	passwordDoNotMatchErr = errors.New("password do not match")
-	uppercaseErr          = errors.New("password must contain at least one uppercase character")
-	lowercaseErr          = errors.New("password must contain at least one lowercase character")
-	specialSymErr         = errors.New("password must contain al least one special symbol")
-	lengthErr             = errors.New(fmt.Sprintf("password must contain at least %d characters", passwordLength))
-	numberErr             = errors.New("password must contain at least one number")
 )
 
 type Request struct {
 	Email     string `json:"email" validate:"required,email"`
-	Password1 string `json:"password1" validate:"required,min=8"`
+	Password1 string `json:"password1" validate:"required"`
 	Password2 string `json:"password2" validate:"required"`
 }
 
@@ -36,7 +24,8 @@ type UserSaver interface {
 	SaveUser(user *domain.User) error
 }
 
-func New(log *slog.Logger, userSaver UserSaver, hasher hash.PasswordHasher) http.HandlerFunc {
+// New creates the account and mails a link to verify its address.
+func New(log *slog.Logger, userSaver UserSaver, hasher hash.PasswordHasher, sender *verify.Sender, policy *password.Policy) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const fn = "handlers.auth.signup.New"
 		log.With(
@@ -53,8 +42,6 @@ func New(log *slog.Logger, userSaver UserSaver, hasher hash.PasswordHasher) http
 			return
 		}
 
-		log.Info("request body decoded", slog.Any("request", req))
-
 		if err := validator.New().Struct(req); err != nil {
 			validationErr := err.(validator.ValidationErrors)
 			log.Error("invalid request", sl.Err(err))
@@ -62,19 +49,20 @@ func New(log *slog.Logger, userSaver UserSaver, hasher hash.PasswordHasher) http
 			return
 		}
 
-		if err := passwordsEqual(req.Password1, req.Password2); err != nil {
-			log.Error(
-				"invalid request",
-				sl.Err(err),
-				slog.String("password_1", req.Password1),
-				slog.String("password_2", req.Password2),
-			)
-			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("passwords do not match"))
+		if err := password.Equal(req.Password1, req.Password2); err != nil {
+			log.Error("invalid request", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error(err.Error()))
 			return
 		}
-		if err := isValidPassword(req.Password1); err != nil {
-			log.Error("failed to validate password", sl.Err(err))
-			customJson.WriteJson(w, http.StatusBadRequest, resp.Error(err.Error()))
+		violations, err := policy.Check(req.Password1, req.Email)
+		if err != nil {
+			log.Error("failed to check password", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		if len(violations) > 0 {
+			log.Info("password rejected by policy", slog.Int("violations", len(violations)))
+			customJson.WriteJson(w, http.StatusBadRequest, password.ViolationsResponse(violations))
 			return
 		}
 		encPassword, err := hasher.Hash(req.Password1)
@@ -94,52 +82,11 @@ func New(log *slog.Logger, userSaver UserSaver, hasher hash.PasswordHasher) http
 			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
 			return
 		}
-		customJson.WriteJson(w, http.StatusOK, resp.OK())
-	}
-}
-
-func passwordsEqual(p1, p2 string) error {
-	if p1 != p2 {
-		return passwordDoNotMatchErr
-	}
-	return nil
-}
-
-func isValidPassword(password string) error {
-	const fn = "handlers.auth.signup.isValidPassword"
-
-	var (
-		upper      bool
-		specialSym bool
-		number     bool
-		lower      bool
-	)
-	if len(password) < passwordLength {
-		return fmt.Errorf("%s : %w", fn, lengthErr)
-	}
-	for _, el := range password {
-		switch {
-		case unicode.IsUpper(el):
-			upper = true
-		case unicode.IsLower(el):
-			lower = true
-		case unicode.IsNumber(el):
-			number = true
-		case unicode.IsSymbol(el) || unicode.IsPunct(el):
-			specialSym = true
+		// The account exists at this point; a lost email can be sent again
+		// through /auth/verify/resend.
+		if err := sender.Send(r.Context(), user); err != nil {
+			log.Error("failed to send verification email", sl.Err(err))
 		}
+		customJson.WriteJson(w, http.StatusOK, resp.OK())
 	}
-	if !upper {
-		return fmt.Errorf("%s : %w", fn, uppercaseErr)
-	}
-	if !lower {
-		return fmt.Errorf("%s : %w", fn, lowercaseErr)
-	}
-	if !specialSym {
-		return fmt.Errorf("%s : %w", fn, specialSymErr)
-	}
-	if !number {
-		return fmt.Errorf("%s : %w", fn, numberErr)
-	}
-	return nil
 }
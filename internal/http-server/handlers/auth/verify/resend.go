@@ -0,0 +1,79 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const mailTimeout = 30 * time.Second
+
+type ResendRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type UserGetter interface {
+	GetUser(email string) (*domain.User, error)
+}
+
+// Resend mails a new verification link. It answers the same whether or
+// not the address belongs to an account, so it cannot be used to probe
+// for registered emails.
+func Resend(log *slog.Logger, userGetter UserGetter, sender *Sender) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.auth.verify.Resend"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		req := new(ResendRequest)
+		if err := customJson.DecodeJson(r, req); err != nil {
+			log.Error("failed to decode json", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("failed to decode request"))
+			return
+		}
+		if err := validator.New().Struct(req); err != nil {
+			validationErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.ValidationError(validationErr))
+			return
+		}
+
+		user, err := userGetter.GetUser(req.Email)
+		switch {
+		case errors.Is(err, storage.ErrUserNotFound):
+			log.Info("no account for verification email")
+		case err != nil:
+			log.Error("failed to get user", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		case user.EmailVerified() || user.Disabled:
+			log.Info("verification email not needed", slog.Int("user_id", user.Id))
+		default:
+			// The mail is sent in the background: waiting for it, or
+			// answering differently when it fails, would tell that the
+			// account exists.
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+				defer cancel()
+				if err := sender.Send(ctx, user); err != nil {
+					log.Error("failed to send verification email", sl.Err(err))
+					return
+				}
+				log.Info("verification email sent", slog.Int("user_id", user.Id))
+			}()
+		}
+
+		customJson.WriteJson(w, http.StatusOK, resp.OK())
+	}
+}
@@ -0,0 +1,57 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/lib/emailtoken"
+	"go_url_chortener_api/internal/lib/jwtkeys"
+	"go_url_chortener_api/internal/lib/mailer"
+	"net/url"
+	"time"
+)
+
+const subject = "Confirm your email address"
+
+// Sender mails verification links.
+type Sender struct {
+	keys     *jwtkeys.Manager
+	mailer   mailer.Mailer
+	lifetime time.Duration
+	// baseURL the links point to. It is never taken from the request: a
+	// forged Host header would send the token to another site.
+	baseURL string
+}
+
+var errNoBaseURL = errors.New("base url not configured")
+
+func NewSender(keys *jwtkeys.Manager, m mailer.Mailer, lifetime time.Duration, baseURL string) *Sender {
+	return &Sender{keys: keys, mailer: m, lifetime: lifetime, baseURL: baseURL}
+}
+
+// Send mails the user a link to GET /auth/verify for their current address.
+func (s *Sender) Send(ctx context.Context, user *domain.User) error {
+	const fn = "handlers.auth.verify.Sender.Send"
+	if s.baseURL == "" {
+		return fmt.Errorf("%s : %w", fn, errNoBaseURL)
+	}
+
+	token, err := emailtoken.Issue(s.keys, user.Id, user.Email, s.lifetime)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+
+	link := s.baseURL + "/auth/verify?token=" + url.QueryEscape(token)
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: subject,
+		Body: fmt.Sprintf("Open this link to confirm your email address:\n\n%s\n\n"+
+			"The link expires in %s. If you did not sign up, ignore this email.\n", link, s.lifetime),
+	})
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
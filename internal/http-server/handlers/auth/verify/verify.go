@@ -0,0 +1,52 @@
+package verify
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/emailtoken"
+	"go_url_chortener_api/internal/lib/jwtkeys"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+)
+
+type EmailVerifier interface {
+	SetEmailVerified(id int, email string) error
+}
+
+// New confirms the address a verification link was mailed to.
+func New(log *slog.Logger, emailVerifier EmailVerifier, keys *jwtkeys.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.auth.verify.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		claims, err := emailtoken.Parse(keys, r.URL.Query().Get("token"))
+		if err != nil {
+			log.Info("invalid verification token", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid or expired token"))
+			return
+		}
+
+		err = emailVerifier.SetEmailVerified(claims.UserId, claims.Email)
+		// the account is gone or its address changed since the link was sent
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("verification token is stale", slog.Int("user_id", claims.UserId))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid or expired token"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to verify email", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		log.Info("email verified", slog.Int("user_id", claims.UserId))
+
+		customJson.WriteJson(w, http.StatusOK, resp.OK())
+	}
+}
@@ -1,30 +1,38 @@
 package del
 
 import (
+	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/http-server/auth"
 	"go_url_chortener_api/internal/http-server/customJson"
 	resp "go_url_chortener_api/internal/lib/api/response"
 	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
 	"log/slog"
 	"net/http"
 )
 
 type URLDeleter interface {
-	DeleteURL(string) error
+	DeleteURL(host string, alias string, userId int) error
 }
 
 func New(log *slog.Logger, deleter URLDeleter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const fn = "handlers.del.New"
-		log.With(
+		log := log.With(
 			slog.String("fn", fn),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
 		alias := getAlias(r)
+		user, _ := auth.UserFrom(r.Context())
 
-		err := deleter.DeleteURL(alias)
+		err := deleter.DeleteURL(r.URL.Query().Get("domain"), alias, user.UserId)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			customJson.WriteJson(w, http.StatusNotFound, resp.Error("url not found"))
+			return
+		}
 		if err != nil {
 			log.Error("failed to delete url", sl.Err(err))
 			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("failed to delete url"))
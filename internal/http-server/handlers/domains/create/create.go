@@ -0,0 +1,93 @@
+package create
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/lib/ownership"
+	"go_url_chortener_api/internal/lib/random"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+)
+
+const tokenLength = 16
+
+type Request struct {
+	Host   string `json:"host" validate:"required,fqdn"`
+	Method string `json:"method" validate:"required,oneof=dns http"`
+}
+
+type Response struct {
+	resp.Response
+	Domain    *domain.Domain         `json:"domain"`
+	Challenge ownership.Instructions `json:"challenge"`
+}
+
+type DomainSaver interface {
+	SaveDomain(d *domain.Domain) error
+}
+
+func New(log *slog.Logger, domainSaver DomainSaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.domains.create.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		req := new(Request)
+		if err := customJson.DecodeJson(r, req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validationErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.ValidationError(validationErr))
+			return
+		}
+
+		user, _ := auth.UserFrom(r.Context())
+		userId := user.UserId
+
+		token, err := random.NewToken(tokenLength)
+		if err != nil {
+			log.Error("failed to generate token", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		d := &domain.Domain{
+			Host:   domain.NormalizeHost(req.Host),
+			UserId: userId,
+			Method: req.Method,
+			Token:  token,
+		}
+		err = domainSaver.SaveDomain(d)
+		if errors.Is(err, storage.ErrDomainExists) {
+			log.Error("domain already exists", slog.String("host", d.Host))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("domain already exists"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to save domain", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		log.Info("domain added", slog.String("host", d.Host))
+
+		customJson.WriteJson(w, http.StatusOK, Response{
+			Response:  resp.OK(),
+			Domain:    d,
+			Challenge: ownership.InstructionsFor(d),
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package list
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"log/slog"
+	"net/http"
+)
+
+type Response struct {
+	resp.Response
+	Domains []domain.Domain `json:"domains"`
+}
+
+type DomainLister interface {
+	GetDomainsByUser(userId int) ([]domain.Domain, error)
+}
+
+func New(log *slog.Logger, domainLister DomainLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.domains.list.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, _ := auth.UserFrom(r.Context())
+		userId := user.UserId
+
+		domains, err := domainLister.GetDomainsByUser(userId)
+		if err != nil {
+			log.Error("failed to get domains", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		customJson.WriteJson(w, http.StatusOK, Response{
+			Response: resp.OK(),
+			Domains:  domains,
+		})
+	}
+}
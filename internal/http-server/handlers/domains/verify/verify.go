@@ -0,0 +1,93 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/lib/ownership"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const verifyTimeout = 10 * time.Second
+
+type DomainVerifier interface {
+	GetDomain(id int) (*domain.Domain, error)
+	MarkDomainVerified(id int) error
+}
+
+type Verifier interface {
+	Verify(ctx context.Context, d *domain.Domain) error
+}
+
+func New(log *slog.Logger, domainVerifier DomainVerifier, verifier Verifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.domains.verify.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid domain id"))
+			return
+		}
+
+		user, _ := auth.UserFrom(r.Context())
+		userId := user.UserId
+
+		d, err := domainVerifier.GetDomain(id)
+		if errors.Is(err, storage.ErrDomainNotFound) || (err == nil && d.UserId != userId) {
+			customJson.WriteJson(w, http.StatusNotFound, resp.Error("domain not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get domain", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		if d.Verified() {
+			customJson.WriteJson(w, http.StatusOK, resp.OK())
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), verifyTimeout)
+		defer cancel()
+
+		if err := verifier.Verify(ctx, d); err != nil {
+			log.Info("domain verification failed", slog.String("host", d.Host), sl.Err(err))
+			if errors.Is(err, ownership.ErrNotVerified) {
+				customJson.WriteJson(w, http.StatusBadRequest, resp.Error("verification challenge not found"))
+				return
+			}
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		err = domainVerifier.MarkDomainVerified(d.Id)
+		if errors.Is(err, storage.ErrDomainExists) {
+			log.Info("domain verified by another user", slog.String("host", d.Host))
+			customJson.WriteJson(w, http.StatusConflict, resp.Error("domain is already verified by another account"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to mark domain verified", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		log.Info("domain verified", slog.String("host", d.Host))
+
+		customJson.WriteJson(w, http.StatusOK, resp.OK())
+	}
+}
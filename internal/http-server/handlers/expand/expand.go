@@ -0,0 +1,126 @@
+package expand
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/clock"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const allowedMethods = "GET, HEAD, OPTIONS"
+
+type Response struct {
+	resp.Response
+	Alias       string            `json:"alias"`
+	ShortURL    string            `json:"shortUrl"`
+	Destination string            `json:"destination,omitempty"`
+	Variants    []string          `json:"variants,omitempty"`
+	LinkStatus  string            `json:"linkStatus"`
+	Title       string            `json:"title,omitempty"`
+	Preview     bool              `json:"preview"`
+	OG          *domain.OpenGraph `json:"og,omitempty"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	StartsAt    *time.Time        `json:"startsAt,omitempty"`
+	ExpiresAt   *time.Time        `json:"expiresAt,omitempty"`
+}
+
+type URLGetter interface {
+	GetURL(host string, alias string) (*domain.URL, error)
+}
+
+// New describes a link without following it or counting a click. It serves
+// GET /api/expand?alias=&domain= and HEAD /{alias}; for HEAD the body is
+// dropped by net/http and the headers carry the answer.
+func New(log *slog.Logger, urlGetter URLGetter, clk clock.Clock, cfg *config.HttpServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.expand.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Link-Status, X-Link-Destination")
+		w.Header().Set("Cache-Control", "no-store")
+
+		alias, host := chi.URLParam(r, "alias"), r.Host
+		if alias == "" {
+			alias, host = r.URL.Query().Get("alias"), r.URL.Query().Get("domain")
+		}
+		if alias == "" {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("field alias is a required field"))
+			return
+		}
+
+		u, err := urlGetter.GetURL(host, alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			customJson.WriteJson(w, http.StatusNotFound, resp.Error("url not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		now := clk.Now()
+		baseURL := cfg.BaseURL
+		if baseURL == "" {
+			baseURL = "http://" + r.Host
+		}
+
+		res := Response{
+			Response:   resp.OK(),
+			Alias:      u.Alias,
+			ShortURL:   u.ShortURL(baseURL),
+			LinkStatus: u.Status(now),
+			Title:      u.Title,
+			Preview:    u.Preview,
+			CreatedAt:  u.CreatedAt,
+			StartsAt:   u.StartsAt,
+			ExpiresAt:  u.ExpiresAt,
+		}
+		// Where a link leads is only told while it can be followed, so
+		// disabled links and links yet to launch stay hidden.
+		if res.LinkStatus == domain.LinkActive {
+			describe(&res, u, now)
+			w.Header().Set("X-Link-Destination", res.Destination)
+		}
+
+		w.Header().Set("X-Link-Status", res.LinkStatus)
+		customJson.WriteJson(w, http.StatusOK, res)
+	}
+}
+
+func describe(res *Response, u *domain.URL, now time.Time) {
+	res.Destination = u.URL
+	if win := u.ActiveWindow(now); win != nil {
+		res.Destination = win.URL
+	} else {
+		for _, v := range u.Variants {
+			res.Variants = append(res.Variants, v.URL)
+		}
+	}
+	if !u.OG.IsEmpty() {
+		res.OG = &u.OG
+	}
+}
+
+// Options answers OPTIONS on the link and expand routes, including CORS
+// preflight requests from browser extensions.
+func Options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", allowedMethods)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
+	w.Header().Set("Access-Control-Max-Age", "86400")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,82 @@
+package expand
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+type stubGetter map[string]*domain.URL
+
+func (s stubGetter) GetURL(_ string, alias string) (*domain.URL, error) {
+	u, ok := s[alias]
+	if !ok {
+		return nil, storage.ErrURLNotFound
+	}
+	return u, nil
+}
+
+func TestExpand(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	variants := []domain.Variant{{Id: 1, URL: "https://a.example.com", Weight: 1}}
+	getter := stubGetter{
+		"live":   {Alias: "live", URL: "https://example.com", Variants: variants},
+		"banned": {Alias: "banned", URL: "https://bad.example.com", Variants: variants, Disabled: true},
+		"soon":   {Alias: "soon", URL: "https://launch.example.com", StartsAt: &later},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := New(log, getter, fixedClock(now), &config.HttpServer{BaseURL: "https://sho.rt"})
+	router := chi.NewRouter()
+	router.Get("/api/expand", handler)
+	router.Head("/{alias}", handler)
+
+	tests := []struct {
+		alias       string
+		status      string
+		destination string
+		variants    int
+	}{
+		{"live", domain.LinkActive, "https://example.com", 1},
+		{"banned", domain.LinkDisabled, "", 0},
+		{"soon", domain.LinkScheduled, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/expand?alias="+tt.alias, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d", rec.Code)
+			}
+			var res Response
+			if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+				t.Fatal(err)
+			}
+			if res.LinkStatus != tt.status || res.Destination != tt.destination || len(res.Variants) != tt.variants {
+				t.Errorf("got %+v", res)
+			}
+
+			rec = httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/"+tt.alias, nil))
+			if got := rec.Header().Get("X-Link-Status"); got != tt.status {
+				t.Errorf("X-Link-Status = %q, want %q", got, tt.status)
+			}
+			if got := rec.Header().Get("X-Link-Destination"); got != tt.destination {
+				t.Errorf("X-Link-Destination = %q, want %q", got, tt.destination)
+			}
+		})
+	}
+}
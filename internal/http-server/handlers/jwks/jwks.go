@@ -0,0 +1,24 @@
+package jwks
+
+import (
+	"go_url_chortener_api/internal/http-server/customJson"
+	"go_url_chortener_api/internal/lib/jwtkeys"
+	"net/http"
+)
+
+// maxAge lets verifiers cache the key set, short enough for them to pick
+// up a new key well before it starts signing.
+const maxAge = "public, max-age=300"
+
+type KeySet interface {
+	JWKS() jwtkeys.JWKSet
+}
+
+// New serves the public keys access tokens are verified with, so other
+// services can check them without sharing a secret.
+func New(keys KeySet) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", maxAge)
+		customJson.WriteJson(w, http.StatusOK, keys.JWKS())
+	}
+}
@@ -0,0 +1,60 @@
+package get
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type Response struct {
+	resp.Response
+	Page *domain.Page `json:"page"`
+}
+
+type PageGetter interface {
+	GetPage(id int) (*domain.Page, error)
+}
+
+func New(log *slog.Logger, pageGetter PageGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.pages.get.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid page id"))
+			return
+		}
+
+		user, _ := auth.UserFrom(r.Context())
+		userId := user.UserId
+
+		page, err := pageGetter.GetPage(id)
+		if errors.Is(err, storage.ErrPageNotFound) || (err == nil && page.UserId != userId) {
+			customJson.WriteJson(w, http.StatusNotFound, resp.Error("page not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get page", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		customJson.WriteJson(w, http.StatusOK, Response{
+			Response: resp.OK(),
+			Page:     page,
+		})
+	}
+}
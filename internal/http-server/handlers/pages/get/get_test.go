@@ -0,0 +1,54 @@
+package get
+
+import (
+	"github.com/go-chi/chi/v5"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type stubGetter map[int]*domain.Page
+
+func (s stubGetter) GetPage(id int) (*domain.Page, error) {
+	p, ok := s[id]
+	if !ok {
+		return nil, storage.ErrPageNotFound
+	}
+	return p, nil
+}
+
+func TestGet(t *testing.T) {
+	getter := stubGetter{1: {Id: 1, UserId: 1, Slug: "alice"}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name   string
+		user   int
+		path   string
+		status int
+	}{
+		{"owner", 1, "/pages/1", http.StatusOK},
+		{"another user's page", 2, "/pages/1", http.StatusNotFound},
+		{"unknown page", 1, "/pages/2", http.StatusNotFound},
+		{"invalid id", 1, "/pages/x", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := chi.NewRouter()
+			router.Get("/pages/{id}", New(log, getter))
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req = req.WithContext(auth.WithUser(req.Context(), auth.Principal{UserId: tt.user}))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
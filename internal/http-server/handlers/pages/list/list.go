@@ -0,0 +1,46 @@
+package list
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"log/slog"
+	"net/http"
+)
+
+type Response struct {
+	resp.Response
+	Pages []domain.Page `json:"pages"`
+}
+
+type PageLister interface {
+	GetPagesByUser(userId int) ([]domain.Page, error)
+}
+
+func New(log *slog.Logger, pageLister PageLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.pages.list.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, _ := auth.UserFrom(r.Context())
+		userId := user.UserId
+
+		pages, err := pageLister.GetPagesByUser(userId)
+		if err != nil {
+			log.Error("failed to get pages", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		customJson.WriteJson(w, http.StatusOK, Response{
+			Response: resp.OK(),
+			Pages:    pages,
+		})
+	}
+}
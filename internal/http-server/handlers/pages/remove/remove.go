@@ -0,0 +1,50 @@
+package remove
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type PageDeleter interface {
+	DeletePage(id int, userId int) error
+}
+
+func New(log *slog.Logger, pageDeleter PageDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.pages.remove.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid page id"))
+			return
+		}
+
+		user, _ := auth.UserFrom(r.Context())
+		userId := user.UserId
+
+		err = pageDeleter.DeletePage(id, userId)
+		if errors.Is(err, storage.ErrPageNotFound) {
+			customJson.WriteJson(w, http.StatusNotFound, resp.Error("page not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to delete page", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		customJson.WriteJson(w, http.StatusOK, resp.OK())
+	}
+}
@@ -0,0 +1,137 @@
+package save
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{2,39}$`)
+
+var errInvalidSlug = errors.New("slug must be 3-40 lowercase letters, digits or dashes")
+
+type Request struct {
+	Slug  string `json:"slug" validate:"required"`
+	Title string `json:"title" validate:"required,max=100"`
+	Theme string `json:"theme,omitempty" validate:"omitempty,oneof=light dark"`
+	// Links are listed on the page in the given order.
+	Links []Link `json:"links" validate:"max=50,dive"`
+}
+
+type Link struct {
+	Alias  string `json:"alias" validate:"required"`
+	Domain string `json:"domain,omitempty"`
+	Title  string `json:"title" validate:"required,max=100"`
+}
+
+type Response struct {
+	resp.Response
+	Page *domain.Page `json:"page"`
+}
+
+type PageSaver interface {
+	SavePage(p *domain.Page) error
+	GetURL(host string, alias string) (*domain.URL, error)
+}
+
+// New creates a page on POST /pages and replaces one on PUT /pages/{id}.
+func New(log *slog.Logger, pageSaver PageSaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.pages.save.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		req := new(Request)
+		if err := customJson.DecodeJson(r, req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validationErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.ValidationError(validationErr))
+			return
+		}
+		if !slugPattern.MatchString(req.Slug) {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error(errInvalidSlug.Error()))
+			return
+		}
+
+		user, _ := auth.UserFrom(r.Context())
+		userId := user.UserId
+
+		page := &domain.Page{
+			UserId: userId,
+			Slug:   req.Slug,
+			Title:  req.Title,
+			Theme:  req.Theme,
+		}
+		if page.Theme == "" {
+			page.Theme = domain.ThemeLight
+		}
+		if id := chi.URLParam(r, "id"); id != "" {
+			var err error
+			if page.Id, err = strconv.Atoi(id); err != nil {
+				customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid page id"))
+				return
+			}
+		}
+
+		for i, l := range req.Links {
+			u, err := pageSaver.GetURL(l.Domain, l.Alias)
+			if errors.Is(err, storage.ErrURLNotFound) || (err == nil && u.UserId != userId) {
+				customJson.WriteJson(w, http.StatusBadRequest, resp.Error(fmt.Sprintf("link %s not found", l.Alias)))
+				return
+			}
+			if err != nil {
+				log.Error("failed to get url", sl.Err(err))
+				customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+				return
+			}
+			page.Links = append(page.Links, domain.PageLink{
+				URLId:    u.Id,
+				Alias:    u.Alias,
+				Host:     u.Host,
+				Title:    l.Title,
+				Position: i,
+			})
+		}
+
+		err := pageSaver.SavePage(page)
+		if errors.Is(err, storage.ErrPageExists) {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("slug is already taken"))
+			return
+		}
+		if errors.Is(err, storage.ErrPageNotFound) {
+			customJson.WriteJson(w, http.StatusNotFound, resp.Error("page not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to save page", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		log.Info("page saved", slog.String("slug", page.Slug))
+
+		customJson.WriteJson(w, http.StatusOK, Response{
+			Response: resp.OK(),
+			Page:     page,
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package save
+
+import (
+	"github.com/go-chi/chi/v5"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// memStore keeps slugs unique and scopes updates to the owner, like
+// postgres.Storage.
+type memStore struct {
+	pages map[int]*domain.Page
+	urls  map[string]*domain.URL
+}
+
+func (m *memStore) SavePage(p *domain.Page) error {
+	for _, other := range m.pages {
+		if other.Slug == p.Slug && other.Id != p.Id {
+			return storage.ErrPageExists
+		}
+	}
+	if p.Id == 0 {
+		p.Id = len(m.pages) + 1
+	} else if old, ok := m.pages[p.Id]; !ok || old.UserId != p.UserId {
+		return storage.ErrPageNotFound
+	}
+	m.pages[p.Id] = p
+	return nil
+}
+
+func (m *memStore) GetURL(_ string, alias string) (*domain.URL, error) {
+	u, ok := m.urls[alias]
+	if !ok {
+		return nil, storage.ErrURLNotFound
+	}
+	return u, nil
+}
+
+func TestSave(t *testing.T) {
+	store := &memStore{
+		pages: map[int]*domain.Page{},
+		urls: map[string]*domain.URL{
+			"mine":   {Id: 1, Alias: "mine", UserId: 1},
+			"theirs": {Id: 2, Alias: "theirs", UserId: 2},
+		},
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := chi.NewRouter()
+	router.Post("/pages", New(log, store))
+	router.Put("/pages/{id}", New(log, store))
+
+	tests := []struct {
+		name   string
+		user   int
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"create", 1, http.MethodPost, "/pages",
+			`{"slug":"alice","title":"Alice","links":[{"alias":"mine","title":"Mine"}]}`, http.StatusOK},
+		{"duplicate slug", 2, http.MethodPost, "/pages",
+			`{"slug":"alice","title":"Not Alice"}`, http.StatusBadRequest},
+		{"another user's page", 2, http.MethodPut, "/pages/1",
+			`{"slug":"alice","title":"Taken over"}`, http.StatusNotFound},
+		{"another user's link", 1, http.MethodPost, "/pages",
+			`{"slug":"alice-2","title":"Alice","links":[{"alias":"theirs","title":"Theirs"}]}`, http.StatusBadRequest},
+		{"invalid slug", 1, http.MethodPost, "/pages",
+			`{"slug":"A!","title":"Alice"}`, http.StatusBadRequest},
+		{"owner updates", 1, http.MethodPut, "/pages/1",
+			`{"slug":"alice","title":"Alice again","theme":"dark"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req = req.WithContext(auth.WithUser(req.Context(), auth.Principal{UserId: tt.user}))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, rec.Code, tt.status, rec.Body)
+		}
+	}
+
+	if p := store.pages[1]; p.UserId != 1 || p.Title != "Alice again" || p.Theme != domain.ThemeDark {
+		t.Errorf("stored page = %+v", p)
+	}
+}
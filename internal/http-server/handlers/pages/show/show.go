@@ -0,0 +1,64 @@
+package show
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/customJson"
+	"go_url_chortener_api/internal/http-server/view"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+)
+
+type link struct {
+	Title string
+	Href  string
+}
+
+type PageGetter interface {
+	GetPageBySlug(slug string) (*domain.Page, error)
+}
+
+// New renders the public page at /p/{slug}. Listed links point at their
+// short URLs, so following one is counted like any other redirect.
+func New(log *slog.Logger, pageGetter PageGetter, cfg *config.HttpServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.pages.show.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		page, err := pageGetter.GetPageBySlug(chi.URLParam(r, "slug"))
+		if errors.Is(err, storage.ErrPageNotFound) {
+			customJson.WriteJson(w, http.StatusNotFound, resp.Error("page not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get page", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		links := make([]link, 0, len(page.Links))
+		for _, l := range page.Links {
+			u := domain.URL{Alias: l.Alias, Host: l.Host}
+			links = append(links, link{Title: l.Title, Href: u.ShortURL(cfg.BaseURL)})
+		}
+
+		err = view.Render(w, http.StatusOK, "page.html", map[string]any{
+			"Title": page.Title,
+			"Theme": page.Theme,
+			"Links": links,
+		})
+		if err != nil {
+			log.Error("failed to render page", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+		}
+	}
+}
@@ -1,36 +1,140 @@
 package redirect
 
 import (
+	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/domain"
 	"go_url_chortener_api/internal/http-server/customJson"
+	"go_url_chortener_api/internal/http-server/view"
 	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/clock"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/lib/useragent"
+	"go_url_chortener_api/internal/storage"
 	"log/slog"
+	"math/rand"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// variantCookie keeps the variant a visitor was sent to, so returning
+// visitors land on the same destination. It is scoped to the alias path.
+const variantCookie = "variant"
+
+const variantCookieMaxAge = 60 * 60 * 24 * 30
+
+// The preview page continues to /{alias}?follow=1, so a click is counted
+// only once the visitor actually goes on. variant carries the variant the
+// page showed, so they land where it said.
+const (
+	followParam  = "follow"
+	variantParam = "variant"
+)
+
+// errorPages are the texts of the HTML pages shown to browsers.
+var errorPages = map[int]struct{ title, message string }{
+	http.StatusNotFound: {
+		"Link not found", "There is no link at this address. Check it for typos.",
+	},
+	http.StatusGone: {
+		"Link expired", "This link has expired and no longer leads anywhere.",
+	},
+	http.StatusUnavailableForLegalReasons: {
+		"Link unavailable", "This link has been disabled.",
+	},
+}
+
+// previewSuffix appended to an alias (GET /{alias}+) shows where the link
+// goes instead of following it.
+const previewSuffix = "+"
+
 type URLGetter interface {
-	GetURL(string) (string, error)
+	GetURL(host string, alias string) (*domain.URL, error)
+	IncrementClicks(urlId int, variantId int) error
 }
 
-func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
+func New(log *slog.Logger, urlGetter URLGetter, clk clock.Clock, cfg *config.Redirect) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const fn = "handlers.redirect.New"
-		log.With(
+		log := log.With(
 			slog.String("fn", fn),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		alias := getAlias(r)
+		alias, inspect := strings.CutSuffix(getAlias(r), previewSuffix)
 
-		urlToResp, err := urlGetter.GetURL(alias)
+		u, err := urlGetter.GetURL(r.Host, alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			writeError(log, w, r, http.StatusNotFound, "url not found")
+			return
+		}
 		if err != nil {
-			log.Error("failed getting url", slog.String("alias", alias))
+			log.Error("failed getting url", slog.String("alias", alias), sl.Err(err))
 			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("failed getting url"))
 			return
 		}
-		log.Info("url sent")
-		log.Info("redirecting...")
+
+		now := clk.Now()
+		if u.Disabled {
+			log.Info("url is disabled", slog.String("alias", alias))
+			writeError(log, w, r, http.StatusUnavailableForLegalReasons, "url has been disabled")
+			return
+		}
+		if u.Expired(now) {
+			log.Info("url has expired", slog.String("alias", alias))
+			writeError(log, w, r, http.StatusGone, "url has expired")
+			return
+		}
+		if u.StartsAt != nil && now.Before(*u.StartsAt) {
+			log.Info("url is not active yet", slog.String("alias", alias))
+			renderNotActive(log, w, r, u)
+			return
+		}
+
+		urlToResp, variantId := destination(r, u, now)
+
+		if inspect {
+			log.Info("showing link preview", slog.String("alias", alias))
+			renderPreview(log, w, u, urlToResp, variantId)
+			return
+		}
+
+		// Social crawlers get the link's card when it has one; humans are
+		// still redirected below.
+		class := useragent.Classify(r.UserAgent())
+		if class == useragent.Unfurler && (!u.OG.IsEmpty() || cfg.UnfurlPreview) {
+			log.Info("serving unfurl preview", slog.String("alias", alias))
+			renderUnfurl(log, w, u, urlToResp)
+			return
+		}
+
+		if u.Preview && !r.URL.Query().Has(followParam) {
+			log.Info("showing link preview", slog.String("alias", alias))
+			renderPreview(log, w, u, urlToResp, variantId)
+			return
+		}
+
+		// Crawlers and previewers are redirected as usual but do not
+		// inflate the click stats.
+		if class == useragent.Human {
+			if err := urlGetter.IncrementClicks(u.Id, variantId); err != nil {
+				log.Error("failed to count click", sl.Err(err))
+			}
+		}
+
+		// The variant only sticks once the visitor was actually sent to it.
+		if variantId != 0 {
+			setVariantCookie(w, u.Alias, variantId)
+		}
+		log.Info("redirecting...",
+			slog.Int("variant_id", variantId),
+			slog.String("client", class.String()),
+		)
 		http.Redirect(w, r, urlToResp, http.StatusFound)
 	}
 }
@@ -39,3 +143,134 @@ func getAlias(r *http.Request) string {
 	alias := chi.URLParam(r, "alias")
 	return alias
 }
+
+// destination resolves where the visitor goes right now: an active window
+// first, then a weighted variant, then the link's own url.
+func destination(r *http.Request, u *domain.URL, now time.Time) (string, int) {
+	// Windows take precedence over variants while they are active.
+	if win := u.ActiveWindow(now); win != nil {
+		return win.URL, 0
+	}
+	if variant := pickVariant(r, u.Variants); variant != nil {
+		return variant.URL, variant.Id
+	}
+	return u.URL, 0
+}
+
+func renderNotActive(log *slog.Logger, w http.ResponseWriter, r *http.Request, u *domain.URL) {
+	if view.AcceptsHTML(r) {
+		err := view.RenderFor(w, r.Host, http.StatusForbidden, "not_active.html", map[string]any{
+			"Alias":    u.Alias,
+			"StartsAt": u.StartsAt.UTC(),
+		})
+		if err == nil {
+			return
+		}
+		log.Error("failed to render page", sl.Err(err))
+	}
+	customJson.WriteJson(w, http.StatusForbidden, resp.Error("url is not active yet"))
+}
+
+// writeError answers a link that cannot be followed. Browsers get an HTML
+// page, branded for the custom domain they came from; API clients keep
+// getting JSON.
+func writeError(log *slog.Logger, w http.ResponseWriter, r *http.Request, statusCode int, msg string) {
+	if page, ok := errorPages[statusCode]; ok && view.AcceptsHTML(r) {
+		err := view.RenderFor(w, r.Host, statusCode, "error.html", map[string]any{
+			"Status":  statusCode,
+			"Title":   page.title,
+			"Message": page.message,
+			"Host":    domain.NormalizeHost(r.Host),
+		})
+		if err == nil {
+			return
+		}
+		log.Error("failed to render page", sl.Err(err))
+	}
+	customJson.WriteJson(w, statusCode, resp.Error(msg))
+}
+
+func renderPreview(log *slog.Logger, w http.ResponseWriter, u *domain.URL, urlToResp string, variantId int) {
+	err := view.Render(w, http.StatusOK, "preview.html", map[string]any{
+		"Alias":       u.Alias,
+		"Title":       u.Title,
+		"Destination": urlToResp,
+		"VariantId":   variantId,
+		"CreatedAt":   u.CreatedAt.UTC(),
+	})
+	if err != nil {
+		log.Error("failed to render page", sl.Err(err))
+		customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+	}
+}
+
+func renderUnfurl(log *slog.Logger, w http.ResponseWriter, u *domain.URL, urlToResp string) {
+	og := u.OG
+	if og.Title == "" {
+		og.Title = u.Title
+	}
+	if og.Title == "" {
+		og.Title = u.Alias
+	}
+	if og.Description == "" {
+		og.Description = urlToResp
+	}
+	err := view.Render(w, http.StatusOK, "unfurl.html", map[string]any{
+		"Title":       og.Title,
+		"Description": og.Description,
+		"Image":       og.Image,
+		"Destination": urlToResp,
+	})
+	if err != nil {
+		log.Error("failed to render page", sl.Err(err))
+		customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+	}
+}
+
+// pickVariant returns the variant named by the preview page or stored in the
+// visitor's cookie if it still exists, otherwise it draws one at random
+// according to the variant weights.
+func pickVariant(r *http.Request, variants []domain.Variant) *domain.Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	chosen := r.URL.Query().Get(variantParam)
+	if cookie, err := r.Cookie(variantCookie); err == nil && chosen == "" {
+		chosen = cookie.Value
+	}
+	if id, err := strconv.Atoi(chosen); err == nil {
+		for i := range variants {
+			if variants[i].Id == id {
+				return &variants[i]
+			}
+		}
+	}
+
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+	if total <= 0 {
+		return &variants[rand.Intn(len(variants))]
+	}
+
+	n := rand.Intn(total)
+	for i := range variants {
+		n -= variants[i].Weight
+		if n < 0 {
+			return &variants[i]
+		}
+	}
+	return &variants[len(variants)-1]
+}
+
+func setVariantCookie(w http.ResponseWriter, alias string, id int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     variantCookie,
+		Value:    strconv.Itoa(id),
+		Path:     "/" + alias,
+		MaxAge:   variantCookieMaxAge,
+		HttpOnly: true,
+	})
+}
@@ -0,0 +1,237 @@
+package redirect
+
+import (
+	"github.com/go-chi/chi/v5"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+type stubGetter struct {
+	urls   map[string]*domain.URL
+	clicks int
+}
+
+func (s *stubGetter) GetURL(_ string, alias string) (*domain.URL, error) {
+	u, ok := s.urls[alias]
+	if !ok {
+		return nil, storage.ErrURLNotFound
+	}
+	return u, nil
+}
+
+func (s *stubGetter) IncrementClicks(int, int) error {
+	s.clicks++
+	return nil
+}
+
+func date(day int) *time.Time {
+	t := time.Date(2024, time.March, day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func TestSchedule(t *testing.T) {
+	getter := &stubGetter{urls: map[string]*domain.URL{
+		"launch": {
+			Id:       1,
+			Alias:    "launch",
+			URL:      "https://store.example.com",
+			StartsAt: date(1),
+			Windows: []domain.Window{
+				{StartsAt: date(1), EndsAt: date(10), URL: "https://presale.example.com"},
+			},
+		},
+	}}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		status   int
+		location string
+	}{
+		{"before start", date(1).Add(-time.Second), http.StatusForbidden, ""},
+		{"inside window", *date(5), http.StatusFound, "https://presale.example.com"},
+		{"window end is exclusive", *date(10), http.StatusFound, "https://store.example.com"},
+		{"after window", *date(20), http.StatusFound, "https://store.example.com"},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := chi.NewRouter()
+			router.Get("/{alias}", New(log, getter, fixedClock(tt.now), &config.Redirect{}))
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/launch", nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Location"); got != tt.location {
+				t.Errorf("location = %q, want %q", got, tt.location)
+			}
+		})
+	}
+}
+
+func TestErrorNegotiation(t *testing.T) {
+	getter := &stubGetter{urls: map[string]*domain.URL{
+		"old": {Id: 1, Alias: "old", URL: "https://example.com", ExpiresAt: date(1)},
+	}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := chi.NewRouter()
+	router.Get("/{alias}", New(log, getter, fixedClock(*date(2)), &config.Redirect{}))
+
+	tests := []struct {
+		name        string
+		path        string
+		accept      string
+		status      int
+		contentType string
+	}{
+		{"browser, unknown alias", "/missing", "text/html,application/xhtml+xml;q=0.9", http.StatusNotFound, "text/html; charset=utf-8"},
+		{"api, unknown alias", "/missing", "application/json", http.StatusNotFound, "application/json"},
+		{"browser, expired", "/old", "text/html", http.StatusGone, "text/html; charset=utf-8"},
+		{"api, expired", "/old", "", http.StatusGone, "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("content type = %q, want %q", got, tt.contentType)
+			}
+		})
+	}
+}
+
+func TestVariants(t *testing.T) {
+	getter := &stubGetter{urls: map[string]*domain.URL{
+		"ab": {Id: 1, Alias: "ab", URL: "https://example.com", Variants: []domain.Variant{
+			{Id: 10, URL: "https://a.example.com", Weight: 0},
+			{Id: 11, URL: "https://b.example.com", Weight: 1},
+		}},
+	}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := chi.NewRouter()
+	router.Get("/{alias}", New(log, getter, fixedClock(*date(1)), &config.Redirect{}))
+
+	tests := []struct {
+		name     string
+		cookie   string
+		location string
+		variant  string
+	}{
+		{"weighted pick", "", "https://b.example.com", "11"},
+		{"sticky cookie", "10", "https://a.example.com", "10"},
+		{"stale cookie", "99", "https://b.example.com", "11"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ab", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: variantCookie, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Location"); got != tt.location {
+				t.Fatalf("location = %q, want %q", got, tt.location)
+			}
+			cookies := rec.Result().Cookies()
+			if len(cookies) != 1 || cookies[0].Value != tt.variant || cookies[0].Path != "/ab" {
+				t.Errorf("cookies = %v, want variant %s on /ab", cookies, tt.variant)
+			}
+		})
+	}
+}
+
+func TestPickVariantWeights(t *testing.T) {
+	variants := []domain.Variant{{Id: 1, Weight: 1}, {Id: 2, Weight: 3}}
+	req := httptest.NewRequest(http.MethodGet, "/ab", nil)
+
+	const draws = 20000
+	counts := map[int]int{}
+	for i := 0; i < draws; i++ {
+		counts[pickVariant(req, variants).Id]++
+	}
+	if share := float64(counts[2]) / draws; share < 0.72 || share > 0.78 {
+		t.Errorf("heavier variant share = %.3f, want about 0.75", share)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	variants := []domain.Variant{{Id: 10, URL: "https://a.example.com", Weight: 1}}
+	getter := &stubGetter{urls: map[string]*domain.URL{
+		"ab":      {Id: 1, Alias: "ab", URL: "https://example.com", Variants: variants},
+		"careful": {Id: 2, Alias: "careful", URL: "https://example.com", Variants: variants, Preview: true},
+	}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := chi.NewRouter()
+	router.Get("/{alias}", New(log, getter, fixedClock(*date(1)), &config.Redirect{}))
+
+	tests := []struct {
+		name   string
+		path   string
+		clicks int
+	}{
+		// showing where a link goes does not count as following it
+		{"inspect suffix", "/ab+", 0},
+		{"preview link", "/careful", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getter.clicks = 0
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "https://a.example.com") {
+				t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+			}
+			if loc := rec.Header().Get("Location"); loc != "" {
+				t.Errorf("redirected to %q", loc)
+			}
+			if cookies := rec.Result().Cookies(); len(cookies) != 0 {
+				t.Errorf("variant cookie set without a redirect: %v", cookies)
+			}
+			if getter.clicks != tt.clicks {
+				t.Errorf("clicks = %d, want %d", getter.clicks, tt.clicks)
+			}
+			if !strings.Contains(rec.Body.String(), `href="/`+strings.TrimSuffix(tt.path[1:], "+")+`?follow=1&amp;variant=10"`) {
+				t.Errorf("no link to follow in %s", rec.Body)
+			}
+		})
+	}
+
+	// continuing from the preview page is the click
+	getter.clicks = 0
+	req := httptest.NewRequest(http.MethodGet, "/careful?follow=1&variant=10", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://a.example.com" {
+		t.Fatalf("follow: status = %d, location = %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if getter.clicks != 1 {
+		t.Errorf("follow: clicks = %d, want 1", getter.clicks)
+	}
+}
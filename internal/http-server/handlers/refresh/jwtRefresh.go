@@ -1,42 +1,89 @@
 package refresh
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
-	"go_url_chortener_api/internal/http-server/middleware"
+	"go_url_chortener_api/internal/lib/jwtkeys"
+	"go_url_chortener_api/internal/lib/random"
 	"net/http"
 	"time"
 )
 
 const refreshTokenLifetime = time.Hour * 24 * 30
 
+// refreshAudience keeps refresh tokens from being accepted as access
+// tokens and the other way round, as both are signed with the same keys.
+const refreshAudience = "refresh"
+
+const (
+	familyIdLength = 16
+	tokenIdLength  = 16
+)
+
 type tokenClaims struct {
 	UserId int `json:"userId"`
-	*jwt.RegisteredClaims
+	jwt.RegisteredClaims
 }
 
-func CreateRefresh(userId int) (string, error) {
+// NewFamily returns the id of a new token family. Every sign-in starts
+// one, so each device rotates its own chain of refresh tokens.
+func NewFamily() (string, error) {
+	return random.NewToken(familyIdLength)
+}
+
+// CreateRefresh issues a refresh token of the family. It returns the token
+// handed to the client and the row to store, which only keeps its hash.
+func CreateRefresh(keys *jwtkeys.Manager, userId int, familyId string) (string, *Token, error) {
+	jti, err := random.NewToken(tokenIdLength)
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	expiresAt := now.Add(refreshTokenLifetime)
 	claims := &tokenClaims{
 		UserId: userId,
-		RegisteredClaims: &jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(refreshTokenLifetime)),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Audience:  jwt.ClaimStrings{refreshAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(middleware.RefreshSecret))
+	raw, err := keys.Sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return raw, &Token{
+		Hash:      HashToken(raw),
+		UserId:    userId,
+		FamilyId:  familyId,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}, nil
 }
 
-func validateRefresh(tknStr string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tknStr, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(middleware.RefreshSecret), nil
-	})
-	if err != nil || !token.Valid {
+// HashToken is what refresh tokens are stored and looked up by.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func validateRefresh(keys *jwtkeys.Manager, tknStr string) (*tokenClaims, error) {
+	claims := new(tokenClaims)
+	_, err := jwt.ParseWithClaims(tknStr, claims, keys.Keyfunc,
+		jwt.WithValidMethods(keys.Methods()),
+		jwt.WithAudience(refreshAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
 		return nil, err
 	}
-	return token, nil
+	if claims.UserId <= 0 {
+		return nil, fmt.Errorf("invalid user id %d", claims.UserId)
+	}
+	return claims, nil
 }
 
 func SetRefreshCookie(w http.ResponseWriter, token string) {
@@ -48,8 +95,19 @@ func SetRefreshCookie(w http.ResponseWriter, token string) {
 	})
 }
 
-func getRefreshCookie(r *http.Request) (string, error) {
-	const fn = "handlers.refresh.getRefreshCookie"
+// ClearRefreshCookie removes the refresh token from the client.
+func ClearRefreshCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		HttpOnly: true,
+		Name:     "refresh-token",
+		Path:     "/",
+		MaxAge:   -1,
+	})
+}
+
+// GetRefreshCookie returns the refresh token sent by the client.
+func GetRefreshCookie(r *http.Request) (string, error) {
+	const fn = "handlers.refresh.GetRefreshCookie"
 	cookie, err := r.Cookie("refresh-token")
 
 	if err != nil {
@@ -1,98 +1,150 @@
 package refresh
 
 import (
-	"github.com/golang-jwt/jwt/v5"
+	"errors"
 	"go_url_chortener_api/internal/domain"
 	"go_url_chortener_api/internal/http-server/customJson"
 	"go_url_chortener_api/internal/http-server/middleware/myJwt"
+	"go_url_chortener_api/internal/lib/api/request"
 	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/jwtkeys"
 	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
 	"log/slog"
 	"net/http"
+	"time"
 )
 
+// Error codes returned with 401 when a refresh token can no longer be used.
+const (
+	CodeReused  = "refresh_token_reused"
+	CodeRevoked = "refresh_token_revoked"
+)
+
+// Token is a stored refresh token. Only the hash of the token is kept.
+// Tokens issued from one sign-in share a family; a token is rotated out
+// when it is exchanged for the next one.
 type Token struct {
-	Id     int    `json:"id"`
-	Token  string `json:"token"`
-	UserId int    `json:"userId"`
+	Id        int
+	Hash      string
+	UserId    int
+	FamilyId  string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RotatedAt *time.Time
+	RevokedAt *time.Time
 }
 
 type Refresher interface {
 	GetUserById(int) (*domain.User, error)
-	GetRefresh(userId int) (*Token, error)
-	DeleteRefresh(token string) error
-	UpdateRefresh(id int, token string) error
+	GetRefresh(hash string) (*Token, error)
+	DeleteRefresh(hash string) error
+	RotateRefresh(id int, next *Token) error
+	RevokeRefreshFamily(familyId string) (int, error)
+	TouchSession(familyId string, ip string) (int, error)
 }
 
 func permissionDenied(w http.ResponseWriter) {
 	customJson.WriteJson(w, http.StatusForbidden, resp.Error("permission denied"))
 }
 
-func New(log *slog.Logger, refresher Refresher) http.HandlerFunc {
+func New(log *slog.Logger, refresher Refresher, tokens *myJwt.Tokens, keys *jwtkeys.Manager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const fn = "handlers.refresh.New"
-		refToken, err := getRefreshCookie(r)
+		refToken, err := GetRefreshCookie(r)
 		if err != nil {
 			log.Error("failed to get refresh cookie", sl.Err(err))
 			customJson.WriteJson(w, http.StatusForbidden, resp.Error("invalid request"))
 			return
 		}
-		token, err := validateRefresh(refToken)
+		hash := HashToken(refToken)
+
+		claims, err := validateRefresh(keys, refToken)
 		if err != nil {
-			if err := refresher.DeleteRefresh(refToken); err != nil {
+			if err := refresher.DeleteRefresh(hash); err != nil {
 				log.Error("failed to delete refresh token", sl.Err(err))
 			}
 			log.Error("failed to validate refresh token", sl.Err(err))
 			customJson.WriteJson(w, http.StatusForbidden, resp.Error("invalid request"))
 			return
 		}
-		claims := token.Claims.(jwt.MapClaims)
 
-		userId := int(claims["userId"].(float64))
-
-		user, err := refresher.GetUserById(userId)
-		if err != nil {
-			log.Error("failed to get user", sl.Err(err))
-			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+		tokenFromStorage, err := refresher.GetRefresh(hash)
+		if errors.Is(err, storage.ErrRefreshNotFound) {
+			log.Info("refresh token not found", slog.Int("user_id", claims.UserId))
+			customJson.WriteJson(w, http.StatusForbidden, resp.Error("invalid request"))
 			return
 		}
-		tokenFromStorage, err := refresher.GetRefresh(user.Id)
 		if err != nil {
 			log.Error("failed to get refresh token", sl.Err(err))
 			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
 			return
 		}
-		if tokenFromStorage.Token != refToken {
-			log.Info("tokens do not match",
-				slog.String("ref1", refToken),
-				slog.String("ref2", tokenFromStorage.Token),
-			)
-		}
 
-		if tokenFromStorage.UserId != userId {
+		if tokenFromStorage.UserId != claims.UserId {
 			permissionDenied(w)
 			return
 		}
+		if tokenFromStorage.RevokedAt != nil {
+			log.Info("refresh token was revoked", slog.String("family", tokenFromStorage.FamilyId))
+			customJson.WriteJson(w, http.StatusUnauthorized, resp.ErrorCode(CodeRevoked, "session has been revoked"))
+			return
+		}
+		if tokenFromStorage.RotatedAt != nil {
+			reused(log, w, refresher, tokens, tokenFromStorage)
+			return
+		}
+
+		user, err := refresher.GetUserById(claims.UserId)
+		if err != nil {
+			log.Error("failed to get user", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		// Sessions are revoked when an account is disabled; this also covers
+		// tokens racing with that revocation.
+		if user.Disabled || user.PasswordResetRequired {
+			log.Info("user may not refresh", slog.Int("user_id", user.Id))
+			customJson.WriteJson(w, http.StatusUnauthorized, resp.ErrorCode(CodeRevoked, "session has been revoked"))
+			return
+		}
 
 		// Creating new refresh token
-		newRefresh, err := CreateRefresh(userId)
+		newRefresh, next, err := CreateRefresh(keys, user.Id, tokenFromStorage.FamilyId)
 		if err != nil {
 			log.Error("failed to create new refresh token", sl.Err(err))
 			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
 			return
 		}
 
-		err = refresher.UpdateRefresh(tokenFromStorage.Id, newRefresh)
+		err = refresher.RotateRefresh(tokenFromStorage.Id, next)
+		if errors.Is(err, storage.ErrRefreshReused) {
+			// Another request rotated the token first.
+			reused(log, w, refresher, tokens, tokenFromStorage)
+			return
+		}
 		if err != nil {
-			log.Error("failed to create new refresh token", sl.Err(err))
+			log.Error("failed to rotate refresh token", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		sessionId, err := refresher.TouchSession(tokenFromStorage.FamilyId, request.ClientIP(r))
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			log.Info("session was revoked", slog.String("family", tokenFromStorage.FamilyId))
+			customJson.WriteJson(w, http.StatusUnauthorized, resp.ErrorCode(CodeRevoked, "session has been revoked"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update session", sl.Err(err))
 			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
 			return
 		}
 
 		// Creating new JWT
-		newJWT, err := myJwt.CreateJWT(user.Id, user.Email)
+		newJWT, err := tokens.CreateJWT(user, sessionId)
 		if err != nil {
-			log.Error("failed to create new refresh token", sl.Err(err))
+			log.Error("failed to create JWT", sl.Err(err))
 			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
 			return
 		}
@@ -100,9 +152,34 @@ func New(log *slog.Logger, refresher Refresher) http.HandlerFunc {
 		myJwt.SetJWTHeader(w, newJWT)
 		SetRefreshCookie(w, newRefresh)
 
-		log.Info("new tokens were set")
+		log.Info("new tokens were set", slog.String("fn", fn))
 		customJson.WriteJson(w, http.StatusOK, map[string]string{
 			"jwt": newJWT,
 		})
 	}
 }
+
+// reused handles a refresh token presented after it was rotated out. Either
+// the client or an attacker holds a stolen copy, and there is no telling
+// which, so the whole family is revoked, along with the access tokens
+// issued to its session, and both have to sign in again.
+func reused(log *slog.Logger, w http.ResponseWriter, refresher Refresher, tokens *myJwt.Tokens, token *Token) {
+	log.Warn("rotated refresh token reused, revoking its family",
+		slog.Int("user_id", token.UserId),
+		slog.String("family", token.FamilyId),
+	)
+	sessionId, err := refresher.RevokeRefreshFamily(token.FamilyId)
+	if err != nil {
+		log.Error("failed to revoke refresh token family", sl.Err(err))
+		customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+		return
+	}
+	if sessionId != 0 {
+		if err := tokens.RevokeSession(sessionId); err != nil {
+			log.Error("failed to revoke access tokens", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+	}
+	customJson.WriteJson(w, http.StatusUnauthorized, resp.ErrorCode(CodeReused, "refresh token has already been used"))
+}
@@ -0,0 +1,133 @@
+package refresh
+
+import (
+	"encoding/json"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/middleware/myJwt"
+	"go_url_chortener_api/internal/lib/clock"
+	"go_url_chortener_api/internal/lib/jwtkeys"
+	"go_url_chortener_api/internal/lib/revocation"
+	"go_url_chortener_api/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type memRefresher struct {
+	tokens []*Token
+}
+
+func (m *memRefresher) GetUserById(id int) (*domain.User, error) {
+	return &domain.User{Id: id, Email: "a@b.c"}, nil
+}
+
+func (m *memRefresher) GetRefresh(hash string) (*Token, error) {
+	for _, t := range m.tokens {
+		if t.Hash == hash {
+			return t, nil
+		}
+	}
+	return nil, storage.ErrRefreshNotFound
+}
+
+func (m *memRefresher) DeleteRefresh(string) error { return nil }
+
+func (m *memRefresher) RotateRefresh(id int, next *Token) error {
+	t := m.tokens[id]
+	if t.RotatedAt != nil || t.RevokedAt != nil {
+		return storage.ErrRefreshReused
+	}
+	now := time.Now()
+	t.RotatedAt = &now
+	next.Id = len(m.tokens)
+	m.tokens = append(m.tokens, next)
+	return nil
+}
+
+func (m *memRefresher) RevokeRefreshFamily(familyId string) (int, error) {
+	now := time.Now()
+	for _, t := range m.tokens {
+		if t.FamilyId == familyId && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return sessionOf(familyId), nil
+}
+
+func (m *memRefresher) TouchSession(familyId string, _ string) (int, error) {
+	return sessionOf(familyId), nil
+}
+
+// sessionOf gives each test family a session of its own.
+func sessionOf(familyId string) int {
+	if familyId == "family" {
+		return 1
+	}
+	return 2
+}
+
+func TestReuseRevokesFamily(t *testing.T) {
+	keys, err := jwtkeys.Ephemeral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := new(memRefresher)
+	revoked := revocation.NewMemory(clock.New(), time.Hour)
+	h := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store,
+		myJwt.NewTokens(&config.Auth{Issuer: "test", Audience: "test_api"}, keys, revoked), keys)
+
+	first, stored, err := CreateRefresh(keys, 1, "family")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.tokens = append(store.tokens, stored)
+	// another device keeps working after the reuse
+	other, stored, err := CreateRefresh(keys, 1, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored.Id = 1
+	store.tokens = append(store.tokens, stored)
+
+	do := func(token string) (int, string, string) {
+		r := httptest.NewRequest(http.MethodGet, "/auth/refresh", nil)
+		r.AddCookie(&http.Cookie{Name: "refresh-token", Value: token})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		var body struct{ Code string }
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		var next string
+		for _, c := range w.Result().Cookies() {
+			if c.Name == "refresh-token" {
+				next = c.Value
+			}
+		}
+		return w.Code, body.Code, next
+	}
+
+	code, _, second := do(first)
+	if code != http.StatusOK || second == "" {
+		t.Fatalf("first refresh: got %d", code)
+	}
+	if code, errCode, _ := do(first); code != http.StatusUnauthorized || errCode != CodeReused {
+		t.Fatalf("reuse: got %d %q, want %d %q", code, errCode, http.StatusUnauthorized, CodeReused)
+	}
+	if code, errCode, _ := do(second); code != http.StatusUnauthorized || errCode != CodeRevoked {
+		t.Fatalf("after reuse: got %d %q, want %d %q", code, errCode, http.StatusUnauthorized, CodeRevoked)
+	}
+	// access tokens already issued to the session stop working too
+	if ok, _ := revoked.IsSessionRevoked(sessionOf("family")); !ok {
+		t.Error("access tokens of the reused session were not revoked")
+	}
+	if code, _, _ := do(other); code != http.StatusOK {
+		t.Fatalf("other family: got %d", code)
+	}
+	if ok, _ := revoked.IsSessionRevoked(sessionOf("other")); ok {
+		t.Error("access tokens of the other session were revoked")
+	}
+}
@@ -0,0 +1,58 @@
+package list
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"log/slog"
+	"net/http"
+)
+
+type Session struct {
+	domain.Session
+	// Current marks the session the request was made from.
+	Current bool `json:"current"`
+}
+
+type Response struct {
+	resp.Response
+	Sessions []Session `json:"sessions"`
+}
+
+type SessionLister interface {
+	GetSessionsByUser(userId int) ([]domain.Session, error)
+}
+
+func New(log *slog.Logger, sessionLister SessionLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.sessions.list.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, _ := auth.UserFrom(r.Context())
+
+		sessions, err := sessionLister.GetSessionsByUser(user.UserId)
+		if err != nil {
+			log.Error("failed to get sessions", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		res := Response{
+			Response: resp.OK(),
+			Sessions: make([]Session, 0, len(sessions)),
+		}
+		for _, s := range sessions {
+			res.Sessions = append(res.Sessions, Session{
+				Session: s,
+				Current: s.Id == user.SessionId,
+			})
+		}
+		customJson.WriteJson(w, http.StatusOK, res)
+	}
+}
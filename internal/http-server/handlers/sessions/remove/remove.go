@@ -0,0 +1,57 @@
+package remove
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	"go_url_chortener_api/internal/http-server/middleware/myJwt"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type SessionRevoker interface {
+	RevokeSession(id int, userId int) error
+}
+
+// New ends a session of the signed-in user: its refresh tokens can no
+// longer be used and the access tokens issued to it are rejected.
+func New(log *slog.Logger, sessionRevoker SessionRevoker, tokens *myJwt.Tokens) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.sessions.remove.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("invalid session id"))
+			return
+		}
+
+		user, _ := auth.UserFrom(r.Context())
+
+		err = sessionRevoker.RevokeSession(id, user.UserId)
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			customJson.WriteJson(w, http.StatusNotFound, resp.Error("session not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to revoke session", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		if err := tokens.RevokeSession(id); err != nil {
+			log.Error("failed to revoke access tokens", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		customJson.WriteJson(w, http.StatusOK, resp.OK())
+	}
+}
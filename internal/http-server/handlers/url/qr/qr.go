@@ -0,0 +1,203 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/cache"
+	"go_url_chortener_api/internal/lib/clock"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/lib/qrcode"
+	"go_url_chortener_api/internal/storage"
+	"image/color"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	formatPNG = "png"
+	formatSVG = "svg"
+
+	defaultSize   = 256
+	minSize       = 64
+	maxSize       = 2048
+	defaultMargin = 4
+	maxMargin     = 20
+
+	cacheSize = 512
+	// maxAge is how long clients may keep a code; it is cut short for
+	// links that expire sooner.
+	maxAge = 24 * time.Hour
+)
+
+// params are the query parameters of a QR request. They are comparable so
+// they double as the cache key together with the encoded content.
+type params struct {
+	content string
+	format  string
+	level   qrcode.Level
+	size    int
+	margin  int
+	fg      color.RGBA
+	bg      color.RGBA
+}
+
+type URLGetter interface {
+	GetURL(host string, alias string) (*domain.URL, error)
+}
+
+// New renders the QR code of a link. Codes always point at the configured
+// base URL and are refused for links that are disabled or have expired.
+func New(log *slog.Logger, urlGetter URLGetter, clk clock.Clock, cfg *config.HttpServer) http.HandlerFunc {
+	images := cache.NewLRU[params, []byte](cacheSize)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.url.qr.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		p, err := parseParams(r)
+		if err != nil {
+			log.Info("invalid qr params", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error(err.Error()))
+			return
+		}
+
+		alias := chi.URLParam(r, "alias")
+		u, err := urlGetter.GetURL(r.URL.Query().Get("domain"), alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			customJson.WriteJson(w, http.StatusNotFound, resp.Error("url not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		now := clk.Now()
+		if status := u.Status(now); status == domain.LinkDisabled || status == domain.LinkExpired {
+			log.Info("no qr code for inactive url", slog.String("alias", alias), slog.String("status", status))
+			customJson.WriteJson(w, http.StatusGone, resp.Error("url is "+status))
+			return
+		}
+		if u.Host == "" && cfg.BaseURL == "" {
+			log.Error("base url is not configured")
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		p.content = u.ShortURL(cfg.BaseURL)
+
+		img, ok := images.Get(p)
+		if !ok {
+			img, err = render(p)
+			if err != nil {
+				log.Error("failed to render qr code", sl.Err(err))
+				customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+				return
+			}
+			images.Add(p, img)
+		}
+
+		contentType := "image/png"
+		if p.format == formatSVG {
+			contentType = "image/svg+xml"
+		}
+		w.Header().Set("Content-Type", contentType)
+		age := maxAge
+		if u.ExpiresAt != nil && u.ExpiresAt.Sub(now) < age {
+			age = u.ExpiresAt.Sub(now)
+		}
+		w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(age.Seconds())))
+		w.Write(img)
+	}
+}
+
+func render(p params) ([]byte, error) {
+	code, err := qrcode.Encode([]byte(p.content), p.level)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := qrcode.RenderOptions{
+		Size:       p.size,
+		Margin:     p.margin,
+		Foreground: p.fg,
+		Background: p.bg,
+	}
+
+	var buf bytes.Buffer
+	if p.format == formatSVG {
+		err = code.SVG(&buf, opts)
+	} else {
+		err = code.PNG(&buf, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func parseParams(r *http.Request) (params, error) {
+	q := r.URL.Query()
+	p := params{
+		format: formatPNG,
+		level:  qrcode.Medium,
+		size:   defaultSize,
+		margin: defaultMargin,
+		fg:     color.RGBA{A: 0xFF},
+		bg:     color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+	}
+
+	if v := q.Get("format"); v != "" {
+		if v != formatPNG && v != formatSVG {
+			return p, errors.New("format must be png or svg")
+		}
+		p.format = v
+	}
+	if v := q.Get("level"); v != "" {
+		level, err := qrcode.ParseLevel(v)
+		if err != nil {
+			return p, errors.New("level must be one of L, M, Q, H")
+		}
+		p.level = level
+	}
+	if v := q.Get("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < minSize || size > maxSize {
+			return p, errors.New("size must be between 64 and 2048")
+		}
+		p.size = size
+	}
+	if v := q.Get("margin"); v != "" {
+		margin, err := strconv.Atoi(v)
+		if err != nil || margin < 0 || margin > maxMargin {
+			return p, errors.New("margin must be between 0 and 20")
+		}
+		p.margin = margin
+	}
+	if v := q.Get("fg"); v != "" {
+		fg, err := qrcode.ParseColor(v)
+		if err != nil {
+			return p, errors.New("fg must be a hex color")
+		}
+		p.fg = fg
+	}
+	if v := q.Get("bg"); v != "" {
+		bg, err := qrcode.ParseColor(v)
+		if err != nil {
+			return p, errors.New("bg must be a hex color")
+		}
+		p.bg = bg
+	}
+	return p, nil
+}
@@ -0,0 +1,68 @@
+package qr
+
+import (
+	"github.com/go-chi/chi/v5"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+type stubGetter map[string]*domain.URL
+
+func (s stubGetter) GetURL(_ string, alias string) (*domain.URL, error) {
+	u, ok := s[alias]
+	if !ok {
+		return nil, storage.ErrURLNotFound
+	}
+	return u, nil
+}
+
+func TestQR(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	soon := now.Add(time.Hour)
+	getter := stubGetter{
+		"live":    {Alias: "live", URL: "https://example.com"},
+		"ending":  {Alias: "ending", URL: "https://example.com", ExpiresAt: &soon},
+		"banned":  {Alias: "banned", URL: "https://example.com", Disabled: true},
+		"expired": {Alias: "expired", URL: "https://example.com", ExpiresAt: &past},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := chi.NewRouter()
+	router.Get("/url/{alias}/qr", New(log, getter, fixedClock(now), &config.HttpServer{BaseURL: "https://sho.rt"}))
+
+	tests := []struct {
+		alias        string
+		code         int
+		cacheControl string
+	}{
+		{"live", http.StatusOK, "private, max-age=86400"},
+		{"ending", http.StatusOK, "private, max-age=3600"},
+		{"banned", http.StatusGone, ""},
+		{"expired", http.StatusGone, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/url/"+tt.alias+"/qr", nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, r)
+			if rec.Code != tt.code {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.code)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != tt.cacheControl {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.cacheControl)
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-playground/validator/v10"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
 	"go_url_chortener_api/internal/http-server/customJson"
 	resp "go_url_chortener_api/internal/lib/api/response"
 	"go_url_chortener_api/internal/lib/logger/sl"
@@ -11,22 +13,56 @@ import (
 	"go_url_chortener_api/internal/storage"
 	"log/slog"
 	"net/http"
+	"time"
 )
 
+// Request is a new link. Destinations and the card image must be http or
+// https URLs; other schemes could run script in the visitor's browser.
 type Request struct {
-	URL   string `json:"url" validate:"required,url"`
-	Alias string `json:"alias,omitempty"`
+	URL       string     `json:"url" validate:"required,http_url"`
+	Alias     string     `json:"alias,omitempty"`
+	Domain    string     `json:"domain,omitempty" validate:"omitempty,fqdn"`
+	Title     string     `json:"title,omitempty" validate:"max=200"`
+	Preview   bool       `json:"preview,omitempty"`
+	OG        OpenGraph  `json:"og"`
+	StartsAt  *time.Time `json:"startsAt,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Variants  []Variant  `json:"variants,omitempty" validate:"omitempty,dive"`
+	Windows   []Window   `json:"windows,omitempty" validate:"omitempty,dive"`
+}
+
+// OpenGraph is the social card served to link previewers.
+type OpenGraph struct {
+	Title       string `json:"title,omitempty" validate:"max=200"`
+	Description string `json:"description,omitempty" validate:"max=500"`
+	Image       string `json:"image,omitempty" validate:"omitempty,http_url"`
+}
+
+// Variant is one weighted destination of an A/B split link.
+type Variant struct {
+	URL    string `json:"url" validate:"required,http_url"`
+	Weight int    `json:"weight" validate:"required,min=1"`
+}
+
+// Window overrides the destination between StartsAt and EndsAt.
+type Window struct {
+	StartsAt *time.Time `json:"startsAt,omitempty"`
+	EndsAt   *time.Time `json:"endsAt,omitempty"`
+	URL      string     `json:"url" validate:"required,http_url"`
 }
 
 const aliasLength = 6
 
+var errInvalidWindow = errors.New("window must end after it starts")
+
 type Response struct {
 	resp.Response
 	Alias string `json:"alias,omitempty"`
 }
 
 type URLSaver interface {
-	SaveURL(urlToSave string, alias string) error
+	SaveURL(u *domain.URL) error
+	GetDomainByHost(host string) (*domain.Domain, error)
 }
 
 func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
@@ -55,11 +91,59 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 			customJson.WriteJson(w, http.StatusBadRequest, resp.ValidationError(validationErr))
 			return
 		}
+		if err := validateWindows(req.Windows); err != nil {
+			log.Error("invalid request", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error(err.Error()))
+			return
+		}
+
+		user, _ := auth.UserFrom(r.Context())
+		userId := user.UserId
+
+		var domainId int
+		if req.Domain != "" {
+			d, err := urlSaver.GetDomainByHost(domain.NormalizeHost(req.Domain))
+			if err != nil && !errors.Is(err, storage.ErrDomainNotFound) {
+				log.Error("failed to get domain", sl.Err(err))
+				customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+				return
+			}
+			if err != nil || d.UserId != userId || !d.Verified() {
+				log.Error("domain is not available", slog.String("domain", req.Domain))
+				customJson.WriteJson(w, http.StatusBadRequest, resp.Error("domain is not verified for this account"))
+				return
+			}
+			domainId = d.Id
+		}
+
 		alias := req.Alias
 		if alias == "" {
 			alias = random.NewRandomString(aliasLength)
 		}
-		err = urlSaver.SaveURL(req.URL, alias)
+
+		u := &domain.URL{
+			Alias:    alias,
+			URL:      req.URL,
+			UserId:   userId,
+			DomainId: domainId,
+			Title:    req.Title,
+			Preview:  req.Preview,
+			OG: domain.OpenGraph{
+				Title:       req.OG.Title,
+				Description: req.OG.Description,
+				Image:       req.OG.Image,
+			},
+			StartsAt:  req.StartsAt,
+			ExpiresAt: req.ExpiresAt,
+		}
+		for _, v := range req.Variants {
+			u.Variants = append(u.Variants, domain.Variant{URL: v.URL, Weight: v.Weight})
+		}
+		for _, win := range req.Windows {
+			u.Windows = append(u.Windows, domain.Window{StartsAt: win.StartsAt, EndsAt: win.EndsAt, URL: win.URL})
+		}
+
+		err = urlSaver.SaveURL(u)
 		if errors.Is(err, storage.ErrURLExists) {
 			log.Error("url already exists", slog.String("url", req.URL))
 			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("url already exists"))
@@ -72,6 +156,18 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 		}
 		log.Info("url added")
 
-		customJson.WriteJson(w, http.StatusOK, resp.OK())
+		customJson.WriteJson(w, http.StatusOK, Response{
+			Response: resp.OK(),
+			Alias:    alias,
+		})
+	}
+}
+
+func validateWindows(windows []Window) error {
+	for _, win := range windows {
+		if win.StartsAt != nil && win.EndsAt != nil && !win.EndsAt.After(*win.StartsAt) {
+			return errInvalidWindow
+		}
 	}
+	return nil
 }
@@ -1 +1,54 @@
 package save
+
+import (
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type stubSaver struct{ saved *domain.URL }
+
+func (s *stubSaver) SaveURL(u *domain.URL) error {
+	s.saved = u
+	return nil
+}
+
+func (s *stubSaver) GetDomainByHost(string) (*domain.Domain, error) {
+	return nil, storage.ErrDomainNotFound
+}
+
+func TestSchemes(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"https", `{"url":"https://example.com"}`, http.StatusOK},
+		{"http", `{"url":"http://example.com/a?b=c"}`, http.StatusOK},
+		{"javascript", `{"url":"javascript:alert(1)"}`, http.StatusBadRequest},
+		{"data", `{"url":"data:text/html,<script>alert(1)</script>"}`, http.StatusBadRequest},
+		{"variant", `{"url":"https://example.com","variants":[{"url":"javascript:alert(1)","weight":1}]}`, http.StatusBadRequest},
+		{"window", `{"url":"https://example.com","windows":[{"url":"javascript:alert(1)"}]}`, http.StatusBadRequest},
+		{"og image", `{"url":"https://example.com","og":{"image":"javascript:alert(1)"}}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saver := new(stubSaver)
+			rec := httptest.NewRecorder()
+			New(log, saver).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/url", strings.NewReader(tt.body)))
+			if rec.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.code, rec.Body)
+			}
+			if tt.code != http.StatusOK && saver.saved != nil {
+				t.Error("link was saved")
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package stats
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+)
+
+type Response struct {
+	resp.Response
+	Alias    string           `json:"alias"`
+	Clicks   int64            `json:"clicks"`
+	Variants []domain.Variant `json:"variants,omitempty"`
+}
+
+type StatsGetter interface {
+	GetURL(host string, alias string) (*domain.URL, error)
+}
+
+func New(log *slog.Logger, statsGetter StatsGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.url.stats.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		user, _ := auth.UserFrom(r.Context())
+
+		u, err := statsGetter.GetURL(r.URL.Query().Get("domain"), alias)
+		if errors.Is(err, storage.ErrURLNotFound) || (err == nil && u.UserId != user.UserId) {
+			customJson.WriteJson(w, http.StatusNotFound, resp.Error("url not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		customJson.WriteJson(w, http.StatusOK, Response{
+			Response: resp.OK(),
+			Alias:    u.Alias,
+			Clicks:   u.Clicks,
+			Variants: u.Variants,
+		})
+	}
+}
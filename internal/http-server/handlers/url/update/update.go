@@ -0,0 +1,106 @@
+package update
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+)
+
+// Request holds the editable link metadata. Fields left out of the body
+// keep their current value.
+type Request struct {
+	Title   *string    `json:"title,omitempty" validate:"omitempty,max=200"`
+	Preview *bool      `json:"preview,omitempty"`
+	OG      *OpenGraph `json:"og,omitempty"`
+}
+
+type OpenGraph struct {
+	Title       *string `json:"title,omitempty" validate:"omitempty,max=200"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=500"`
+	Image       *string `json:"image,omitempty" validate:"omitempty,http_url"`
+}
+
+type URLUpdater interface {
+	GetURL(host string, alias string) (*domain.URL, error)
+	UpdateURL(u *domain.URL) error
+}
+
+func New(log *slog.Logger, urlUpdater URLUpdater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.url.update.New"
+		log := log.With(
+			slog.String("fn", fn),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		req := new(Request)
+		if err := customJson.DecodeJson(r, req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validationErr := err.(validator.ValidationErrors)
+			log.Error("invalid request", sl.Err(err))
+			customJson.WriteJson(w, http.StatusBadRequest, resp.ValidationError(validationErr))
+			return
+		}
+
+		user, _ := auth.UserFrom(r.Context())
+
+		u, err := urlUpdater.GetURL(r.URL.Query().Get("domain"), alias)
+		if errors.Is(err, storage.ErrURLNotFound) || (err == nil && u.UserId != user.UserId) {
+			customJson.WriteJson(w, http.StatusNotFound, resp.Error("url not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+
+		apply(u, req)
+
+		if err := urlUpdater.UpdateURL(u); err != nil {
+			log.Error("failed to update url", sl.Err(err))
+			customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+			return
+		}
+		log.Info("url updated", slog.String("alias", alias))
+
+		customJson.WriteJson(w, http.StatusOK, resp.OK())
+	}
+}
+
+func apply(u *domain.URL, req *Request) {
+	if req.Title != nil {
+		u.Title = *req.Title
+	}
+	if req.Preview != nil {
+		u.Preview = *req.Preview
+	}
+	if req.OG == nil {
+		return
+	}
+	if req.OG.Title != nil {
+		u.OG.Title = *req.OG.Title
+	}
+	if req.OG.Description != nil {
+		u.OG.Description = *req.OG.Description
+	}
+	if req.OG.Image != nil {
+		u.OG.Image = *req.OG.Image
+	}
+}
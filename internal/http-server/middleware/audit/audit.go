@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/lib/api/request"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"log/slog"
+	"net/http"
+)
+
+type Recorder interface {
+	SaveAuditEntry(e *domain.AuditEntry) error
+}
+
+// New records every request of the route group in the audit log, once the
+// response has been written. It must be mounted after the authentication
+// middleware; mounted before the role check, it also records refused
+// attempts.
+func New(log *slog.Logger, recorder Recorder) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			// Requests refused by a later middleware of the group are never
+			// routed to their handler, so the route is matched here.
+			route := routeOf(r)
+			p, _ := auth.UserFrom(r.Context())
+			entry := &domain.AuditEntry{
+				ActorId:  p.UserId,
+				Action:   r.Method + " " + route.RoutePattern(),
+				TargetId: route.URLParam("id"),
+				Query:    r.URL.RawQuery,
+				Status:   status,
+				IP:       request.ClientIP(r),
+			}
+			if err := recorder.SaveAuditEntry(entry); err != nil {
+				log.Error("failed to write audit log", sl.Err(err),
+					slog.Int("actor_id", entry.ActorId),
+					slog.String("action", entry.Action),
+					slog.String("target_id", entry.TargetId),
+				)
+			}
+		})
+	}
+}
+
+// routeOf matches the request against the whole router without serving it.
+func routeOf(r *http.Request) *chi.Context {
+	route := chi.NewRouteContext()
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return route
+	}
+	if !rctx.Routes.Match(route, r.Method, r.URL.Path) {
+		return rctx
+	}
+	return route
+}
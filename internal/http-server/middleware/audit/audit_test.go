@@ -0,0 +1,73 @@
+package audit
+
+import (
+	"github.com/go-chi/chi/v5"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type memRecorder struct{ entries []domain.AuditEntry }
+
+func (m *memRecorder) SaveAuditEntry(e *domain.AuditEntry) error {
+	m.entries = append(m.entries, *e)
+	return nil
+}
+
+func TestRefusedAttemptsAreAudited(t *testing.T) {
+	recorder := &memRecorder{}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	router := chi.NewRouter()
+	router.Route("/admin", func(r chi.Router) {
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				role := r.Header.Get("X-Test-Role")
+				next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), auth.Principal{UserId: 5, Role: role})))
+			})
+		})
+		r.Use(New(log, recorder))
+		r.Use(auth.RequireRole(domain.RoleAdmin))
+		r.Post("/users/{id}/disable", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	tests := []struct {
+		role   string
+		status int
+	}{
+		{domain.RoleUser, http.StatusForbidden},
+		{domain.RoleAdmin, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/admin/users/9/disable?reason=spam", nil)
+		req.RemoteAddr = "203.0.113.7:5000"
+		req.Header.Set("X-Test-Role", tt.role)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Fatalf("%s: status = %d, want %d", tt.role, rec.Code, tt.status)
+		}
+	}
+
+	if len(recorder.entries) != 2 {
+		t.Fatalf("got %d audit entries, want 2", len(recorder.entries))
+	}
+	want := domain.AuditEntry{
+		ActorId:  5,
+		Action:   "POST /admin/users/{id}/disable",
+		TargetId: "9",
+		Query:    "reason=spam",
+		Status:   http.StatusForbidden,
+		IP:       "203.0.113.7",
+	}
+	if got := recorder.entries[0]; got != want {
+		t.Errorf("refused attempt recorded as %+v, want %+v", got, want)
+	}
+	if got := recorder.entries[1].Status; got != http.StatusOK {
+		t.Errorf("admin request recorded with status %d", got)
+	}
+}
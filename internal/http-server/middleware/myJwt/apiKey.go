@@ -0,0 +1,60 @@
+package myJwt
+
+import (
+	"errors"
+	"fmt"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/lib/apikey"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/storage"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var errInvalidAPIKey = errors.New("invalid api key")
+
+type APIKeyGetter interface {
+	GetAPIKeyByPrefix(prefix string) (*domain.APIKey, error)
+	TouchAPIKey(id int) error
+}
+
+// AuthMiddleware is JwtMiddleware that also accepts personal API keys sent
+// as "Authorization: ApiKey <key>".
+func AuthMiddleware(log *slog.Logger, tokens *Tokens, apiKeys APIKeyGetter) func(next http.Handler) http.Handler {
+	return authMiddleware(log, func(r *http.Request) (auth.Principal, error) {
+		scheme, key, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		if strings.EqualFold(scheme, "ApiKey") {
+			return authenticateAPIKey(log, apiKeys, key)
+		}
+		return tokens.Authenticate(r)
+	})
+}
+
+func authenticateAPIKey(log *slog.Logger, apiKeys APIKeyGetter, key string) (auth.Principal, error) {
+	prefix, err := apikey.Prefix(key)
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	k, err := apiKeys.GetAPIKeyByPrefix(prefix)
+	if errors.Is(err, storage.ErrAPIKeyNotFound) {
+		return auth.Principal{}, errInvalidAPIKey
+	}
+	if err != nil {
+		return auth.Principal{}, fmt.Errorf("%w: %w", errStore, err)
+	}
+	if !apikey.Matches(key, k.Hash) || k.Expired(time.Now()) {
+		return auth.Principal{}, errInvalidAPIKey
+	}
+
+	if err := apiKeys.TouchAPIKey(k.Id); err != nil {
+		log.Error("failed to record api key use", sl.Err(err))
+	}
+	return auth.Principal{
+		UserId:   k.UserId,
+		APIKeyId: k.Id,
+		Scopes:   k.Scopes,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package myJwt
+
+import (
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/lib/apikey"
+	"go_url_chortener_api/internal/storage"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type keyGetter map[string]*domain.APIKey
+
+func (g keyGetter) GetAPIKeyByPrefix(prefix string) (*domain.APIKey, error) {
+	if k, ok := g[prefix]; ok {
+		return k, nil
+	}
+	return nil, storage.ErrAPIKeyNotFound
+}
+
+func (g keyGetter) TouchAPIKey(int) error { return nil }
+
+func TestAuthMiddlewareAPIKey(t *testing.T) {
+	key, prefix, err := apikey.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiredKey, expiredPrefix, _ := apikey.Generate()
+	past := time.Now().Add(-time.Hour)
+	keys := keyGetter{
+		prefix:        {Id: 1, UserId: 7, Prefix: prefix, Hash: apikey.Hash(key), Scopes: []string{auth.ScopeLinksRead}},
+		expiredPrefix: {Id: 2, UserId: 7, Prefix: expiredPrefix, Hash: apikey.Hash(expiredKey), ExpiresAt: &past},
+	}
+
+	wrong := []byte(key)
+	wrong[len(wrong)-1] ^= 1
+
+	var got auth.Principal
+	h := AuthMiddleware(slog.New(slog.NewTextHandler(io.Discard, nil)), newTokens(t), keys)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, _ = auth.UserFrom(r.Context())
+		}))
+	do := func(header string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", header)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := do("ApiKey " + key); code != http.StatusOK || got.UserId != 7 || got.APIKeyId != 1 {
+		t.Fatalf("got %d %+v", code, got)
+	}
+	for name, header := range map[string]string{
+		"expired":    "ApiKey " + expiredKey,
+		"wrong hash": "ApiKey " + string(wrong),
+		"malformed":  "ApiKey nope",
+		"as bearer":  "Bearer " + key,
+	} {
+		if code := do(header); code != http.StatusUnauthorized {
+			t.Errorf("%s: got %d, want %d", name, code, http.StatusUnauthorized)
+		}
+	}
+}
@@ -1,12 +1,18 @@
 package myJwt
 
 import (
+	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
 	"go_url_chortener_api/internal/http-server/customJson"
-	"go_url_chortener_api/internal/http-server/middleware"
 	"go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/jwtkeys"
 	"go_url_chortener_api/internal/lib/logger/sl"
+	"go_url_chortener_api/internal/lib/random"
+	"go_url_chortener_api/internal/lib/revocation"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -14,111 +20,243 @@ import (
 	"time"
 )
 
-const jwtTokenLifetime = time.Minute * 15
+const (
+	// TokenLifetime is how long access tokens are valid.
+	TokenLifetime = time.Minute * 15
+	// clockSkew tolerated when checking exp, iat and nbf
+	clockSkew = 30 * time.Second
+
+	tokenIdLength = 16
+)
+
+var (
+	ErrRevoked = errors.New("token has been revoked")
+
+	errInvalidClaims = errors.New("invalid token claims")
+	// errStore means the credentials could not be checked; the request is
+	// refused but it is not the client's fault.
+	errStore = errors.New("credential store failed")
+)
 
 type jwtClaims struct {
 	Id    int    `json:"id"`
 	Email string `json:"email"`
+	Role  string `json:"role,omitempty"`
+	// Scope is the space separated list of granted scopes.
+	Scope string `json:"scope,omitempty"`
+	// Sid is the session the token was issued to.
+	Sid int `json:"sid,omitempty"`
+	// IatMicro is the issue time in microseconds. iat only has second
+	// precision, too coarse to tell tokens issued just before a
+	// revocation from those issued just after it.
+	IatMicro int64 `json:"iat_us,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func permissionDenied(w http.ResponseWriter) {
-	customJson.WriteJson(w, http.StatusForbidden, response.Error("authorization failed"))
+// Tokens issues, validates and revokes access tokens.
+type Tokens struct {
+	cfg     *config.Auth
+	keys    *jwtkeys.Manager
+	revoked revocation.Store
+}
+
+func NewTokens(cfg *config.Auth, keys *jwtkeys.Manager, revoked revocation.Store) *Tokens {
+	return &Tokens{cfg: cfg, keys: keys, revoked: revoked}
+}
+
+func authorizationFailed(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	customJson.WriteJson(w, http.StatusUnauthorized, response.Error("authorization failed"))
+}
+
+// JwtMiddleware authenticates requests by their bearer token and stores
+// the user it was issued to in the request context, see auth.UserFrom.
+func JwtMiddleware(log *slog.Logger, tokens *Tokens) func(next http.Handler) http.Handler {
+	return authMiddleware(log, tokens.Authenticate)
 }
 
-func JwtMiddleware(log *slog.Logger) func(next http.Handler) http.Handler {
+func authMiddleware(log *slog.Logger, authenticate func(r *http.Request) (auth.Principal, error)) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			tokenStr, err := getJWT(r)
-			if err != nil {
-				log.Error("failed to get JWT", sl.Err(err))
-				permissionDenied(w)
+			principal, err := authenticate(r)
+			if errors.Is(err, errStore) {
+				log.Error("failed to authenticate", sl.Err(err))
+				customJson.WriteJson(w, http.StatusInternalServerError, response.Error("server error"))
 				return
 			}
-			token, err := validateJWT(tokenStr)
 			if err != nil {
-				log.Error("failed to validate JWT", sl.Err(err))
-				permissionDenied(w)
-				return
-			}
-			id, err := getIdCookie(r)
-			if err != nil {
-				log.Error("failed get Id cookie", sl.Err(err))
-				permissionDenied(w)
-				return
-			}
-			tokenClaims := token.Claims.(jwt.MapClaims)
-			if int(tokenClaims["id"].(float64)) != id {
-				log.Error("id's from claims and from cookie do not match", sl.Err(err))
-				permissionDenied(w)
+				log.Error("failed to authenticate", sl.Err(err))
+				authorizationFailed(w)
 				return
 			}
-			log.Info("jwt middleware ended successfully")
-			next.ServeHTTP(w, r)
+
+			ctx := auth.WithUser(r.Context(), principal)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// Authenticate returns the user the bearer token of the request was issued
+// to, unless the token is invalid or has been revoked.
+func (t *Tokens) Authenticate(r *http.Request) (auth.Principal, error) {
+	tokenStr, err := getJWT(r)
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	claims, err := t.validateJWT(tokenStr)
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	if err := t.checkRevoked(claims); err != nil {
+		return auth.Principal{}, err
+	}
+	return auth.Principal{
+		UserId:    claims.Id,
+		Email:     claims.Email,
+		Role:      claims.Role,
+		Scopes:    strings.Fields(claims.Scope),
+		SessionId: claims.Sid,
+		TokenId:   claims.ID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+// checkRevoked rejects revoked tokens, tokens of ended sessions and tokens
+// issued before the user's watermark. Tokens without iat_us only have
+// second precision, so those issued within the second of the watermark are
+// rejected too.
+func (t *Tokens) checkRevoked(claims *jwtClaims) error {
+	revoked, err := t.revoked.IsTokenRevoked(claims.ID)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errStore, err)
+	}
+	if revoked {
+		return ErrRevoked
+	}
+	if claims.Sid != 0 {
+		revoked, err := t.revoked.IsSessionRevoked(claims.Sid)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errStore, err)
+		}
+		if revoked {
+			return ErrRevoked
+		}
+	}
+	before, err := t.revoked.TokensRevokedBefore(claims.Id)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errStore, err)
+	}
+	if before.IsZero() {
+		return nil
+	}
+	if claims.IatMicro != 0 {
+		if claims.IatMicro < before.UnixMicro() {
+			return ErrRevoked
+		}
+		return nil
+	}
+	if !claims.IssuedAt.Time.After(before.Truncate(time.Second)) {
+		return ErrRevoked
+	}
+	return nil
+}
+
+// Revoke invalidates the access token the principal authenticated with.
+func (t *Tokens) Revoke(p auth.Principal) error {
+	return t.revoked.RevokeToken(p.TokenId, p.ExpiresAt)
+}
+
+// RevokeSession invalidates every access token issued to the session. The
+// entry outlives the last of them by the tolerated clock skew.
+func (t *Tokens) RevokeSession(sessionId int) error {
+	return t.revoked.RevokeSessionTokens(sessionId, time.Now().Add(TokenLifetime+clockSkew))
+}
+
+// RevokeUser invalidates every access token issued to the user so far.
+func (t *Tokens) RevokeUser(userId int) error {
+	return t.revoked.RevokeTokensBefore(userId, watermark())
+}
+
+// RevokeOthers invalidates every access token issued to the user so far and
+// returns a new one for the session the request is made from, so the caller
+// stays signed in.
+func (t *Tokens) RevokeOthers(user *domain.User, sessionId int) (string, error) {
+	now := watermark()
+	if err := t.revoked.RevokeTokensBefore(user.Id, now); err != nil {
+		return "", err
+	}
+	return t.createJWT(user, sessionId, now)
+}
+
+// watermark is the current time at the precision of iat_us. Postgres would
+// round a finer time to the nearest microsecond, possibly past the issue
+// time of a token created right after it.
+func watermark() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
 func getJWT(r *http.Request) (string, error) {
 	tokenBearer := r.Header.Get("Authorization")
 	if tokenBearer == "" {
 		return "", fmt.Errorf("token is empty")
 	}
-	token := strings.Split(tokenBearer, " ")
-	if len(token) != 2 {
+	scheme, token, ok := strings.Cut(tokenBearer, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
 		return "", fmt.Errorf("token is invalid: %s", tokenBearer)
 	}
-	return token[1], nil
+	return token, nil
 }
 
-func getIdCookie(r *http.Request) (int, error) {
-	cookie, err := r.Cookie("id")
-	if err != nil {
-		return -1, fmt.Errorf("failed to get id cookie")
-	}
-	id, err := strconv.Atoi(cookie.Value)
-	if err != nil {
-		return -1, fmt.Errorf("invalid id")
-	}
-	return id, err
+func (t *Tokens) CreateJWT(user *domain.User, sessionId int) (string, error) {
+	return t.createJWT(user, sessionId, time.Now())
 }
 
-func CreateJWT(id int, email string) (string, error) {
+func (t *Tokens) createJWT(user *domain.User, sessionId int, now time.Time) (string, error) {
+	jti, err := random.NewToken(tokenIdLength)
+	if err != nil {
+		return "", err
+	}
 	claims := jwtClaims{
-		Id:    id,
-		Email: email,
+		Id:       user.Id,
+		Email:    user.Email,
+		Role:     user.Role,
+		Scope:    strings.Join(auth.UserScopes, " "),
+		Sid:      sessionId,
+		IatMicro: now.UnixMicro(),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(jwtTokenLifetime)),
+			ID:        jti,
+			Subject:   strconv.Itoa(user.Id),
+			Issuer:    t.cfg.Issuer,
+			Audience:  jwt.ClaimStrings{t.cfg.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(TokenLifetime)),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(middleware.JwtSecret))
+	return t.keys.Sign(claims)
 }
 
 func SetJWTHeader(w http.ResponseWriter, token string) {
 	w.Header().Set("Authorization", "Bearer "+token)
 }
 
-func SetIdCookie(w http.ResponseWriter, id int) {
-	idStr := strconv.Itoa(id)
-	http.SetCookie(w, &http.Cookie{
-		Name:     "id",
-		Value:    idStr,
-		Path:     "/",
-		HttpOnly: true,
-	})
-}
-
-func validateJWT(tknStr string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tknStr, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(middleware.JwtSecret), nil
-	})
-	if err != nil || !token.Valid {
+func (t *Tokens) validateJWT(tknStr string) (*jwtClaims, error) {
+	claims := new(jwtClaims)
+	_, err := jwt.ParseWithClaims(tknStr, claims, t.keys.Keyfunc,
+		jwt.WithValidMethods(t.keys.Methods()),
+		jwt.WithIssuer(t.cfg.Issuer),
+		jwt.WithAudience(t.cfg.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
 		return nil, err
 	}
-	return token, nil
+	if claims.Id <= 0 || claims.Subject != strconv.Itoa(claims.Id) ||
+		claims.ID == "" || claims.IssuedAt == nil {
+		return nil, errInvalidClaims
+	}
+	return claims, nil
 }
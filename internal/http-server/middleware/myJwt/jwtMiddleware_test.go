@@ -0,0 +1,161 @@
+package myJwt
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/lib/clock"
+	"go_url_chortener_api/internal/lib/jwtkeys"
+	"go_url_chortener_api/internal/lib/revocation"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var (
+	testCfg = &config.Auth{Issuer: "test", Audience: "test_api"}
+	alice   = &domain.User{Id: 7, Email: "a@b.c", Role: domain.RoleAdmin}
+	bob     = &domain.User{Id: 8, Email: "d@e.f", Role: domain.RoleUser}
+)
+
+func newTokens(t *testing.T) *Tokens {
+	t.Helper()
+	keys, err := jwtkeys.Ephemeral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewTokens(testCfg, keys, revocation.NewMemory(clock.New(), TokenLifetime))
+}
+
+func serve(t *testing.T, tokens *Tokens, token string) (int, auth.Principal) {
+	t.Helper()
+
+	var got auth.Principal
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = auth.UserFrom(r.Context())
+	})
+	h := JwtMiddleware(slog.New(slog.NewTextHandler(io.Discard, nil)), tokens)(next)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Code, got
+}
+
+func sign(t *testing.T, tokens *Tokens, claims jwt.Claims) string {
+	t.Helper()
+	s, err := tokens.keys.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestJwtMiddleware(t *testing.T) {
+	tokens := newTokens(t)
+	valid, err := tokens.CreateJWT(alice, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, p := serve(t, tokens, valid)
+	if code != http.StatusOK || p.UserId != 7 || p.Email != "a@b.c" || p.SessionId != 3 ||
+		p.Role != domain.RoleAdmin || !p.HasScope(auth.ScopeLinksWrite) {
+		t.Fatalf("valid token: got %d %+v", code, p)
+	}
+
+	exp := jwt.NewNumericDate(time.Now().Add(time.Minute))
+	tests := map[string]string{
+		"garbage": "not-a-token",
+		// used to panic on the float64 type assertion
+		"string id": sign(t, tokens, jwt.MapClaims{
+			"id": "7", "sub": "7", "iss": "test", "aud": "test_api", "exp": exp,
+		}),
+		"wrong audience": sign(t, tokens, jwt.MapClaims{
+			"id": 7, "sub": "7", "iss": "test", "aud": "other", "exp": exp,
+		}),
+		"no expiry": sign(t, tokens, jwt.MapClaims{
+			"id": 7, "sub": "7", "iss": "test", "aud": "test_api",
+		}),
+		"subject mismatch": sign(t, tokens, jwt.MapClaims{
+			"id": 7, "sub": "8", "iss": "test", "aud": "test_api", "exp": exp,
+		}),
+	}
+	// an HMAC token keyed with something public must not pass
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id": 7, "sub": "7", "iss": "test", "aud": "test_api", "exp": exp,
+	})
+	hmac.Header["kid"] = tokens.keys.JWKS().Keys[0].Kid
+	tests["hs256"], _ = hmac.SignedString([]byte(tokens.keys.JWKS().Keys[0].X))
+	// a token from another key set
+	tests["foreign key"], _ = newTokens(t).CreateJWT(alice, 3)
+
+	for name, token := range tests {
+		if code, _ := serve(t, tokens, token); code != http.StatusUnauthorized {
+			t.Errorf("%s: got %d, want %d", name, code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestRevocation(t *testing.T) {
+	tokens := newTokens(t)
+	first, _ := tokens.CreateJWT(alice, 1)
+	second, _ := tokens.CreateJWT(alice, 2)
+	other, _ := tokens.CreateJWT(bob, 3)
+
+	code, p := serve(t, tokens, first)
+	if code != http.StatusOK {
+		t.Fatalf("got %d", code)
+	}
+	if err := tokens.Revoke(p); err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := serve(t, tokens, first); code != http.StatusUnauthorized {
+		t.Errorf("revoked token: got %d", code)
+	}
+	if code, _ := serve(t, tokens, second); code != http.StatusOK {
+		t.Errorf("other token of the user: got %d", code)
+	}
+
+	third, _ := tokens.CreateJWT(alice, 3)
+	if err := tokens.RevokeSession(2); err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := serve(t, tokens, second); code != http.StatusUnauthorized {
+		t.Errorf("token of an ended session: got %d", code)
+	}
+	if code, _ := serve(t, tokens, third); code != http.StatusOK {
+		t.Errorf("token of another session: got %d", code)
+	}
+
+	if err := tokens.RevokeUser(7); err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := serve(t, tokens, third); code != http.StatusUnauthorized {
+		t.Errorf("token issued before the watermark: got %d", code)
+	}
+	if code, _ := serve(t, tokens, other); code != http.StatusOK {
+		t.Errorf("token of another user: got %d", code)
+	}
+	// signing in again right away must not be caught by the watermark
+	again, _ := tokens.CreateJWT(alice, 4)
+	if code, _ := serve(t, tokens, again); code != http.StatusOK {
+		t.Errorf("token issued after the watermark: got %d", code)
+	}
+
+	fresh, err := tokens.RevokeOthers(bob, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := serve(t, tokens, other); code != http.StatusUnauthorized {
+		t.Errorf("token replaced by RevokeOthers: got %d", code)
+	}
+	if code, p := serve(t, tokens, fresh); code != http.StatusOK || p.SessionId != 3 {
+		t.Errorf("token issued by RevokeOthers: got %d %+v", code, p)
+	}
+}
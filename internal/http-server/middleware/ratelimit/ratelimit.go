@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/http-server/customJson"
+	"go_url_chortener_api/internal/lib/api/request"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// idleTimeout is how long a client may stay silent before its bucket is dropped.
+const idleTimeout = 10 * time.Minute
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// limiter is a token bucket per client IP: buckets refill at rate tokens
+// per second up to burst, and every request takes one token.
+type limiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// New limits requests per client IP. It relies on the realip middleware
+// having set RemoteAddr from the headers of trusted proxies.
+func New(log *slog.Logger, cfg *config.RateLimit) func(next http.Handler) http.Handler {
+	l := &limiter{
+		rate:    cfg.RPS,
+		burst:   float64(cfg.Burst),
+		buckets: make(map[string]*bucket),
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := request.ClientIP(r)
+			if wait, ok := l.allow(ip, time.Now()); !ok {
+				log.Info("rate limit exceeded", slog.String("ip", ip), slog.String("path", r.URL.Path))
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				customJson.WriteJson(w, http.StatusTooManyRequests, resp.Error("too many requests"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allow takes a token from the bucket of key. When the bucket is empty it
+// returns how long until the next token is available.
+func (l *limiter) allow(key string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst}
+		l.buckets[key] = b
+	} else {
+		b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.lastSeen).Seconds()*l.rate)
+	}
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleTimeout {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"go_url_chortener_api/internal/config"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	l := &limiter{rate: 2, burst: 2, buckets: make(map[string]*bucket)}
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < 2; i++ {
+		if _, ok := l.allow("a", now); !ok {
+			t.Fatalf("request %d within burst refused", i)
+		}
+	}
+	wait, ok := l.allow("a", now)
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("over burst: ok = %v, wait = %v", ok, wait)
+	}
+	if _, ok := l.allow("b", now); !ok {
+		t.Error("other client refused")
+	}
+	if _, ok := l.allow("a", now.Add(500*time.Millisecond)); !ok {
+		t.Error("refilled token refused")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := New(log, &config.RateLimit{RPS: 0.5, Burst: 1})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	codes := make([]int, 0, 2)
+	var retryAfter string
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/expand", nil))
+		codes = append(codes, rec.Code)
+		retryAfter = rec.Header().Get("Retry-After")
+	}
+	if codes[0] != http.StatusNoContent || codes[1] != http.StatusTooManyRequests {
+		t.Fatalf("codes = %v", codes)
+	}
+	if retryAfter != "2" {
+		t.Errorf("Retry-After = %q, want 2", retryAfter)
+	}
+}
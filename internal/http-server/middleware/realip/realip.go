@@ -0,0 +1,89 @@
+package realip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Proxies is the set of addresses whose forwarding headers are trusted.
+type Proxies []netip.Prefix
+
+// Parse reads proxy entries given either as a single address or as a
+// CIDR range.
+func Parse(entries []string) (Proxies, error) {
+	const fn = "middleware.realip.Parse"
+	proxies := make(Proxies, 0, len(entries))
+	for _, e := range entries {
+		if p, err := netip.ParsePrefix(e); err == nil {
+			proxies = append(proxies, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(e)
+		if err != nil {
+			return nil, fmt.Errorf("%s : invalid proxy %q", fn, e)
+		}
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return proxies, nil
+}
+
+func (p Proxies) contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// New sets RemoteAddr to the client address reported by X-Forwarded-For
+// or X-Real-IP, but only for requests coming from one of the proxies.
+// Anyone else could put any address in those headers, so their own
+// address is kept.
+func New(proxies Proxies) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip, ok := proxies.clientIP(r); ok {
+				r.RemoteAddr = ip
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (p Proxies) clientIP(r *http.Request) (string, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil || !p.contains(peer) {
+		return "", false
+	}
+
+	// Every proxy appends the address it got the request from, so the
+	// client is the last hop that is not one of ours.
+	var client netip.Addr
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = addr
+		if !p.contains(addr) {
+			break
+		}
+	}
+	if client.IsValid() {
+		return client.Unmap().String(), true
+	}
+	if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return addr.Unmap().String(), true
+	}
+	return "", false
+}
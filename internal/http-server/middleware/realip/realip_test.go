@@ -0,0 +1,52 @@
+package realip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	proxies, err := Parse([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Parse([]string{"proxy.local"}); err == nil {
+		t.Error("host name accepted as proxy")
+	}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    string
+		realIP string
+		want   string
+	}{
+		{"direct client", "203.0.113.7:5000", "", "", "203.0.113.7:5000"},
+		{"spoofed header from client", "203.0.113.7:5000", "1.2.3.4", "1.2.3.4", "203.0.113.7:5000"},
+		{"trusted proxy", "10.1.2.3:443", "198.51.100.9", "", "198.51.100.9"},
+		{"client prepends a fake hop", "10.1.2.3:443", "1.2.3.4, 198.51.100.9, 10.4.4.4", "", "198.51.100.9"},
+		{"x-real-ip from proxy", "192.168.1.1:80", "", "198.51.100.9", "198.51.100.9"},
+		{"proxy without headers", "10.1.2.3:443", "", "", "10.1.2.3:443"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := New(proxies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package verified
+
+import (
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/http-server/auth"
+	"go_url_chortener_api/internal/http-server/customJson"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"go_url_chortener_api/internal/lib/logger/sl"
+	"log/slog"
+	"net/http"
+)
+
+// CodeEmailNotVerified is returned to users who have not confirmed their
+// email address yet.
+const CodeEmailNotVerified = "email_not_verified"
+
+type UserGetter interface {
+	GetUserById(id int) (*domain.User, error)
+}
+
+// New refuses requests of users whose email address is not verified. It
+// must be mounted after the authentication middleware. The user is looked
+// up on every request, so a verification takes effect on tokens issued
+// before it.
+func New(log *slog.Logger, userGetter UserGetter) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, _ := auth.UserFrom(r.Context())
+			user, err := userGetter.GetUserById(p.UserId)
+			if err != nil {
+				log.Error("failed to get user", sl.Err(err))
+				customJson.WriteJson(w, http.StatusInternalServerError, resp.Error("server error"))
+				return
+			}
+			if !user.EmailVerified() {
+				customJson.WriteJson(w, http.StatusForbidden,
+					resp.ErrorCode(CodeEmailNotVerified, "email address is not verified"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
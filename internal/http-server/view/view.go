@@ -0,0 +1,119 @@
+package view
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"go_url_chortener_api/internal/domain"
+	"html/template"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//go:embed templates/*.html
+var files embed.FS
+
+var (
+	templates = template.Must(template.ParseFS(files, "templates/*.html"))
+	// hostTemplates hold per custom domain overrides, keyed by host.
+	hostTemplates = map[string]*template.Template{}
+)
+
+// Load overrides the embedded templates with the *.html files found in dir.
+// Templates in a subdirectory named after a custom domain, e.g.
+// dir/go.acme.com/error.html, are only used for that domain. Load must be
+// called before the server starts; an empty dir keeps the defaults.
+func Load(dir string) error {
+	const fn = "http-server.view.Load"
+
+	if dir == "" {
+		return nil
+	}
+
+	base, err := override(templates, dir)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+
+	hosts := make(map[string]*template.Template)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		t, err := override(base, filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("%s : %w", fn, err)
+		}
+		hosts[domain.NormalizeHost(entry.Name())] = t
+	}
+
+	templates = base
+	hostTemplates = hosts
+	return nil
+}
+
+func override(t *template.Template, dir string) (*template.Template, error) {
+	clone, err := t.Clone()
+	if err != nil {
+		return nil, err
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil || len(matches) == 0 {
+		return clone, err
+	}
+	return clone.ParseFiles(matches...)
+}
+
+// Render executes the named template and writes it as an HTML response.
+// The page is rendered into a buffer first so a template error does not
+// leave a half-written response behind.
+func Render(w http.ResponseWriter, statusCode int, name string, data any) error {
+	return render(w, templates, statusCode, name, data)
+}
+
+// RenderFor is Render using the templates of the custom domain serving
+// host, when it has any.
+func RenderFor(w http.ResponseWriter, host string, statusCode int, name string, data any) error {
+	t, ok := hostTemplates[domain.NormalizeHost(host)]
+	if !ok {
+		t = templates
+	}
+	return render(w, t, statusCode, name, data)
+}
+
+func render(w http.ResponseWriter, t *template.Template, statusCode int, name string, data any) error {
+	const fn = "http-server.view.Render"
+
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(statusCode)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// AcceptsHTML reports whether the client asked for HTML, as browsers do.
+// API clients sending no Accept header or */* get JSON.
+func AcceptsHTML(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package request
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// ClientIP returns the address of the client without the port. Behind a
+// trusted proxy it relies on the realip middleware having set RemoteAddr.
+func ClientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+var ErrInvalidPage = errors.New("limit and offset must be non-negative numbers")
+
+// Page reads the limit and offset query parameters of a listing. The limit
+// defaults to DefaultLimit and is capped at MaxLimit.
+func Page(r *http.Request) (limit int, offset int, err error) {
+	limit, offset = DefaultLimit, 0
+	q := r.URL.Query()
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			return 0, 0, ErrInvalidPage
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return 0, 0, ErrInvalidPage
+		}
+	}
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	return limit, offset, nil
+}
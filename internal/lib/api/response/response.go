@@ -9,6 +9,8 @@ import (
 type Response struct {
 	Status string `json:"status"`
 	Error  string `json:"error,omitempty"`
+	// Code is a stable identifier for outcomes clients need to tell apart.
+	Code string `json:"code,omitempty"`
 }
 
 const (
@@ -29,6 +31,14 @@ func Error(msg string) Response {
 	}
 }
 
+func ErrorCode(code string, msg string) Response {
+	return Response{
+		Status: StatusError,
+		Error:  msg,
+		Code:   code,
+	}
+}
+
 func ValidationError(errs validator.ValidationErrors) Response {
 	var errMsgs []string
 
@@ -38,6 +48,8 @@ func ValidationError(errs validator.ValidationErrors) Response {
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is a required field", err.Field()))
 		case "url":
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not a valid URL", err.Field()))
+		case "http_url":
+			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not a valid http or https URL", err.Field()))
 		case "email":
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not a valid email", err.Field()))
 		default:
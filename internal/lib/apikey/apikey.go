@@ -0,0 +1,53 @@
+package apikey
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"go_url_chortener_api/internal/lib/random"
+	"strings"
+)
+
+// Keys look like gus_<prefix>_<secret>. The marker makes leaked keys easy
+// to find with secret scanners.
+const (
+	marker       = "gus"
+	prefixLength = 4
+	secretLength = 24
+)
+
+var ErrMalformed = errors.New("malformed api key")
+
+// Generate returns a new key and its prefix.
+func Generate() (key string, prefix string, err error) {
+	prefix, err = random.NewToken(prefixLength)
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := random.NewToken(secretLength)
+	if err != nil {
+		return "", "", err
+	}
+	return marker + "_" + prefix + "_" + secret, prefix, nil
+}
+
+// Prefix returns the prefix a key is stored under.
+func Prefix(key string) (string, error) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != marker ||
+		len(parts[1]) != 2*prefixLength || len(parts[2]) != 2*secretLength {
+		return "", ErrMalformed
+	}
+	return parts[1], nil
+}
+
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Matches compares a key against a stored hash in constant time.
+func Matches(key string, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(Hash(key)), []byte(hash)) == 1
+}
@@ -0,0 +1,19 @@
+package clock
+
+import "time"
+
+// Clock is the source of the current time. Handlers take it as a dependency
+// so time-based behaviour can be tested with a fixed clock.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func New() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
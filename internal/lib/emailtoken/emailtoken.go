@@ -0,0 +1,56 @@
+package emailtoken
+
+import (
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"go_url_chortener_api/internal/lib/jwtkeys"
+	"strconv"
+	"time"
+)
+
+// audience keeps verification tokens from being accepted as access or
+// refresh tokens, as all of them are signed with the same keys.
+const audience = "email_verify"
+
+var ErrInvalidClaims = errors.New("invalid verification token claims")
+
+// Claims bind the token to the address it was sent to, so a link mailed
+// before an email change cannot verify the new address.
+type Claims struct {
+	UserId int    `json:"userId"`
+	Email  string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// Issue returns a token proving that whoever holds it received mail at
+// email, valid for lifetime.
+func Issue(keys *jwtkeys.Manager, userId int, email string, lifetime time.Duration) (string, error) {
+	now := time.Now()
+	return keys.Sign(&Claims{
+		UserId: userId,
+		Email:  email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(userId),
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(lifetime)),
+		},
+	})
+}
+
+// Parse checks the signature and expiry of a token returned by Issue.
+func Parse(keys *jwtkeys.Manager, token string) (*Claims, error) {
+	claims := new(Claims)
+	_, err := jwt.ParseWithClaims(token, claims, keys.Keyfunc,
+		jwt.WithValidMethods(keys.Methods()),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if claims.UserId <= 0 || claims.Email == "" || claims.Subject != strconv.Itoa(claims.UserId) {
+		return nil, ErrInvalidClaims
+	}
+	return claims, nil
+}
@@ -0,0 +1,45 @@
+package emailtoken
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"go_url_chortener_api/internal/lib/jwtkeys"
+	"testing"
+	"time"
+)
+
+func TestIssueParse(t *testing.T) {
+	keys, err := jwtkeys.Ephemeral()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := Issue(keys, 7, "a@b.c", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := Parse(keys, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserId != 7 || claims.Email != "a@b.c" {
+		t.Fatalf("got %+v", claims)
+	}
+
+	expired, _ := Issue(keys, 7, "a@b.c", -time.Minute)
+	other, _ := jwtkeys.Ephemeral()
+	foreign, _ := Issue(other, 7, "a@b.c", time.Hour)
+	refresh, _ := keys.Sign(jwt.RegisteredClaims{
+		Subject:   "7",
+		Audience:  jwt.ClaimStrings{"refresh"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	for name, token := range map[string]string{
+		"expired":        expired,
+		"foreign key":    foreign,
+		"wrong audience": refresh,
+	} {
+		if _, err := Parse(keys, token); err == nil {
+			t.Errorf("%s: parsed", name)
+		}
+	}
+}
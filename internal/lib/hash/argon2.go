@@ -0,0 +1,106 @@
+package hash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"strings"
+)
+
+const (
+	argon2Prefix = "$argon2id$"
+
+	saltLength = 16
+	keyLength  = 32
+)
+
+var errInvalidArgon2 = errors.New("invalid argon2id hash")
+
+// Argon2Params are the argon2id cost parameters. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// Argon2Hasher hashes passwords with argon2id into the PHC string format
+// also used by the reference implementation:
+//
+//	$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+//
+// The parameters are stored with each hash, so they can be raised without
+// breaking existing passwords.
+type Argon2Hasher struct {
+	params Argon2Params
+}
+
+func NewArgon2Hasher(params Argon2Params) *Argon2Hasher {
+	return &Argon2Hasher{params: params}
+}
+
+func (h *Argon2Hasher) Hash(password string) (string, error) {
+	const fn = "lib.hash.Argon2Hasher.Hash"
+
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("%s : %w", fn, err)
+	}
+	p := h.params
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, keyLength)
+
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version,
+		p.Memory, p.Iterations, p.Parallelism, enc.EncodeToString(salt), enc.EncodeToString(key)), nil
+}
+
+func (h *Argon2Hasher) CheckPassword(hashedPassword string, password string) error {
+	return checkPassword(hashedPassword, password)
+}
+
+func (h *Argon2Hasher) NeedsRehash(hashedPassword string) bool {
+	p, _, key, err := decodeArgon2(hashedPassword)
+	return err != nil || p != h.params || len(key) != keyLength
+}
+
+func checkArgon2(hashedPassword string, password string) error {
+	p, salt, key, err := decodeArgon2(hashedPassword)
+	if err != nil {
+		return err
+	}
+	other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+func decodeArgon2(hashedPassword string) (Argon2Params, []byte, []byte, error) {
+	var p Argon2Params
+	parts := strings.Split(strings.TrimPrefix(hashedPassword, argon2Prefix), "$")
+	if len(parts) != 4 {
+		return p, nil, nil, errInvalidArgon2
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[0], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errInvalidArgon2
+	}
+	_, err := fmt.Sscanf(parts[1], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism)
+	if err != nil || p.Iterations == 0 || p.Parallelism == 0 {
+		return p, nil, nil, errInvalidArgon2
+	}
+
+	enc := base64.RawStdEncoding
+	salt, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return p, nil, nil, errInvalidArgon2
+	}
+	key, err := enc.DecodeString(parts[3])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, errInvalidArgon2
+	}
+	return p, salt, key, nil
+}
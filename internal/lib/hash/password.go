@@ -1,15 +1,44 @@
 package hash
 
 import (
+	"errors"
 	"fmt"
+	"go_url_chortener_api/internal/config"
 	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+var (
+	ErrMismatch      = errors.New("password does not match")
+	ErrUnknownFormat = errors.New("unknown password hash format")
 )
 
 type PasswordHasher interface {
 	Hash(string) (string, error)
+	// CheckPassword accepts hashes of every supported format, so stored
+	// passwords keep working when the configured algorithm changes.
 	CheckPassword(hash string, password string) error
+	// NeedsRehash reports whether the hash is not in the configured format
+	// and should be replaced the next time the password is known.
+	NeedsRehash(hash string) bool
 }
 
+// New returns the hasher of the configured algorithm.
+func New(cfg *config.PasswordHash) (PasswordHasher, error) {
+	switch cfg.Algorithm {
+	case "argon2id":
+		return NewArgon2Hasher(Argon2Params{
+			Memory:      cfg.Memory,
+			Iterations:  cfg.Iterations,
+			Parallelism: cfg.Parallelism,
+		}), nil
+	case "bcrypt":
+		return NewSHA1Hasher(cfg.BcryptCost), nil
+	}
+	return nil, fmt.Errorf("lib.hash.New : %w: %q", ErrUnknownFormat, cfg.Algorithm)
+}
+
+// SHA1Hasher despite its name hashes with bcrypt; salt is the bcrypt cost.
 type SHA1Hasher struct {
 	salt int
 }
@@ -30,11 +59,42 @@ func (h *SHA1Hasher) Hash(password string) (string, error) {
 }
 
 func (h *SHA1Hasher) CheckPassword(hashedPassword string, password string) error {
+	return checkPassword(hashedPassword, password)
+}
+
+func (h *SHA1Hasher) NeedsRehash(hashedPassword string) bool {
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost != h.salt
+}
+
+// checkPassword verifies the password against a hash of any supported
+// format, told apart by its prefix.
+func checkPassword(hashedPassword string, password string) error {
 	const fn = "lib.hash.CheckPassword"
 
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	var err error
+	switch {
+	case strings.HasPrefix(hashedPassword, argon2Prefix):
+		err = checkArgon2(hashedPassword, password)
+	case isBcrypt(hashedPassword):
+		err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			err = ErrMismatch
+		}
+	default:
+		err = ErrUnknownFormat
+	}
 	if err != nil {
 		return fmt.Errorf("%s : %w", fn, err)
 	}
 	return nil
 }
+
+func isBcrypt(hashedPassword string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hashedPassword, prefix) {
+			return true
+		}
+	}
+	return false
+}
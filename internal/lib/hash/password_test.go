@@ -0,0 +1,93 @@
+package hash
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// testParams keep the tests fast; see the benchmarks for real ones.
+var testParams = Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1}
+
+func TestCheckPassword(t *testing.T) {
+	argon := NewArgon2Hasher(testParams)
+	bcrypt := NewSHA1Hasher(4)
+
+	for name, h := range map[string]PasswordHasher{"argon2id": argon, "bcrypt": bcrypt} {
+		hash, err := h.Hash("secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// either hasher verifies both formats
+		for _, checker := range []PasswordHasher{argon, bcrypt} {
+			if err := checker.CheckPassword(hash, "secret"); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+			if err := checker.CheckPassword(hash, "wrong"); !errors.Is(err, ErrMismatch) {
+				t.Errorf("%s wrong password: got %v", name, err)
+			}
+		}
+	}
+
+	for _, bad := range []string{"", "plain", "$argon2id$v=19$m=1,t=1$x$y", "$argon2id$v=18$m=1024,t=1,p=1$AAAA$AAAA"} {
+		if err := argon.CheckPassword(bad, "secret"); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argon := NewArgon2Hasher(testParams)
+	current, _ := argon.Hash("secret")
+	weaker, _ := NewArgon2Hasher(Argon2Params{Memory: 512, Iterations: 1, Parallelism: 1}).Hash("secret")
+	old, _ := NewSHA1Hasher(4).Hash("secret")
+
+	if argon.NeedsRehash(current) {
+		t.Error("current hash needs rehash")
+	}
+	if !argon.NeedsRehash(weaker) {
+		t.Error("hash with other parameters does not need rehash")
+	}
+	if !argon.NeedsRehash(old) {
+		t.Error("bcrypt hash does not need rehash")
+	}
+	if !NewSHA1Hasher(5).NeedsRehash(old) {
+		t.Error("bcrypt hash with lower cost does not need rehash")
+	}
+}
+
+// BenchmarkArgon2 times one hash per parameter set. Pick the strongest set
+// that stays well under the sign-in latency budget (~100-250ms) on the
+// production hardware:
+//
+//	go test ./internal/lib/hash -bench Argon2 -benchtime 10x
+func BenchmarkArgon2(b *testing.B) {
+	for _, p := range []Argon2Params{
+		{Memory: 19 * 1024, Iterations: 2, Parallelism: 1},
+		{Memory: 64 * 1024, Iterations: 1, Parallelism: 4},
+		{Memory: 64 * 1024, Iterations: 3, Parallelism: 2},
+		{Memory: 128 * 1024, Iterations: 3, Parallelism: 4},
+	} {
+		h := NewArgon2Hasher(p)
+		b.Run(fmt.Sprintf("m=%d,t=%d,p=%d", p.Memory, p.Iterations, p.Parallelism), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := h.Hash("correct horse battery staple"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkBcrypt(b *testing.B) {
+	for _, cost := range []int{10, 12} {
+		h := NewSHA1Hasher(cost)
+		b.Run(fmt.Sprintf("cost=%d", cost), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := h.Hash("correct horse battery staple"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
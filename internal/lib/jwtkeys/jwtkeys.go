@@ -0,0 +1,216 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"go_url_chortener_api/internal/config"
+	"math/big"
+	"os"
+)
+
+var (
+	ErrUnknownKey     = errors.New("unknown signing key")
+	ErrNoSigningKey   = errors.New("signing key not configured")
+	ErrUnsupportedKey = errors.New("unsupported key type")
+)
+
+// Key is one entry of the key set. Private is nil for keys that are only
+// kept to verify tokens issued before a rotation.
+type Key struct {
+	Id      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// Manager signs tokens with the current signing key and verifies them
+// with any key of the set, picked by the kid header.
+type Manager struct {
+	signing string
+	keys    map[string]*Key
+	order   []string
+}
+
+func NewManager(signingId string, keys ...*Key) (*Manager, error) {
+	const fn = "lib.jwtkeys.NewManager"
+	m := &Manager{
+		signing: signingId,
+		keys:    make(map[string]*Key, len(keys)),
+	}
+	for _, k := range keys {
+		if _, ok := m.keys[k.Id]; ok {
+			return nil, fmt.Errorf("%s: duplicate key id %q", fn, k.Id)
+		}
+		m.keys[k.Id] = k
+		m.order = append(m.order, k.Id)
+	}
+	if k, ok := m.keys[signingId]; !ok || k.Private == nil {
+		return nil, fmt.Errorf("%s: %q: %w", fn, signingId, ErrNoSigningKey)
+	}
+	return m, nil
+}
+
+// Load reads the key set from the PEM files listed in the auth config.
+func Load(cfg *config.Auth) (*Manager, error) {
+	const fn = "lib.jwtkeys.Load"
+	keys := make([]*Key, 0, len(cfg.Keys))
+	for _, kc := range cfg.Keys {
+		data, err := os.ReadFile(kc.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		k, err := ParsePEM(kc.Id, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", fn, kc.Path, err)
+		}
+		keys = append(keys, k)
+	}
+	return NewManager(cfg.SigningKey, keys...)
+}
+
+// Ephemeral returns a manager holding a freshly generated Ed25519 key.
+// Tokens it issues do not survive a restart; it is meant for local runs
+// without configured keys.
+func Ephemeral() (*Manager, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	k := &Key{
+		Id:      hex.EncodeToString(id),
+		Method:  jwt.SigningMethodEdDSA,
+		Private: priv,
+		Public:  pub,
+	}
+	return NewManager(k.Id, k)
+}
+
+// ParsePEM accepts an RSA or Ed25519 private key (PKCS#1 or PKCS#8) or a
+// PKIX public key for verification only.
+func ParsePEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	k := &Key{Id: id}
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		k.Method, k.Private, k.Public = jwt.SigningMethodRS256, key, &key.PublicKey
+	case *rsa.PublicKey:
+		k.Method, k.Public = jwt.SigningMethodRS256, key
+	case ed25519.PrivateKey:
+		k.Method, k.Private, k.Public = jwt.SigningMethodEdDSA, key, key.Public()
+	case ed25519.PublicKey:
+		k.Method, k.Public = jwt.SigningMethodEdDSA, key
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, parsed)
+	}
+	return k, nil
+}
+
+// Sign returns the claims signed with the current signing key, with its
+// id in the kid header.
+func (m *Manager) Sign(claims jwt.Claims) (string, error) {
+	k := m.keys[m.signing]
+	token := jwt.NewWithClaims(k.Method, claims)
+	token.Header["kid"] = k.Id
+	return token.SignedString(k.Private)
+}
+
+// Keyfunc resolves the verification key of a token for jwt.Parse.
+func (m *Manager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	k, ok := m.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	if token.Method.Alg() != k.Method.Alg() {
+		return nil, fmt.Errorf("key %q does not accept %s", kid, token.Method.Alg())
+	}
+	return k.Public, nil
+}
+
+// Methods lists the algorithms of the key set, for jwt.WithValidMethods.
+func (m *Manager) Methods() []string {
+	var methods []string
+	seen := make(map[string]bool)
+	for _, id := range m.order {
+		alg := m.keys[id].Method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// JWK is a public key in the RFC 7517 JSON format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS publishes every key of the set so other services can verify the
+// tokens, including ones signed with a key that is being rotated out.
+func (m *Manager) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(m.order))}
+	enc := base64.RawURLEncoding
+	for _, id := range m.order {
+		k := m.keys[id]
+		jwk := JWK{Kid: k.Id, Use: "sig", Alg: k.Method.Alg()}
+		switch pub := k.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = enc.EncodeToString(pub.N.Bytes())
+			jwk.E = enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = enc.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
@@ -0,0 +1,89 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"testing"
+)
+
+func pemOf(t *testing.T, typ string, der []byte, err error) []byte {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+}
+
+func TestRotation(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldKey, err := ParsePEM("old", pemOf(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	newKey, err := ParsePEM("new", pemOf(t, "PRIVATE KEY", der, err))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := NewManager("old", oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := before.Sign(jwt.MapClaims{"sub": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// After the rotation only the public half of the old key is kept.
+	der, err = x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	oldPublic, err := ParsePEM("old", pemOf(t, "PUBLIC KEY", der, err))
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := NewManager("new", newKey, oldPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := after.Sign(jwt.MapClaims{"sub": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range []string{oldToken, newToken} {
+		if _, err := jwt.Parse(token, after.Keyfunc, jwt.WithValidMethods(after.Methods())); err != nil {
+			t.Errorf("token rejected after rotation: %v", err)
+		}
+	}
+	if _, err := jwt.Parse(newToken, before.Keyfunc); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("got %v, want %v", err, ErrUnknownKey)
+	}
+
+	if _, err := NewManager("old", oldPublic); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("public key used for signing: got %v", err)
+	}
+
+	set := after.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("got %d keys, want 2", len(set.Keys))
+	}
+	if k := set.Keys[0]; k.Kid != "new" || k.Kty != "OKP" || k.Alg != "EdDSA" || k.X == "" {
+		t.Errorf("unexpected Ed25519 JWK: %+v", k)
+	}
+	if k := set.Keys[1]; k.Kid != "old" || k.Kty != "RSA" || k.Alg != "RS256" || k.E != "AQAB" {
+		t.Errorf("unexpected RSA JWK: %+v", k)
+	}
+}
@@ -2,8 +2,8 @@ package sl
 
 import "log/slog"
 
-func Err(err error) *slog.Attr {
-	return &slog.Attr{
+func Err(err error) slog.Attr {
+	return slog.Attr{
 		Key:   "error",
 		Value: slog.StringValue(err.Error()),
 	}
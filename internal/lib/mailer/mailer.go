@@ -0,0 +1,147 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_url_chortener_api/internal/config"
+	"go_url_chortener_api/internal/env"
+	"io"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownTransport = errors.New("unknown mail transport")
+	ErrNoTransport      = errors.New("mail transport not configured")
+	errHeaderInjection  = errors.New("line break in mail header")
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Load returns the mailer for the configured transport. Without one,
+// messages are printed to stdout in the local environment; elsewhere that
+// would put verification and reset links in the logs, so it fails.
+func Load(cfg *config.Mail, environment string) (Mailer, error) {
+	const fn = "lib.mailer.Load"
+	transport := cfg.Transport
+	if transport == "" {
+		if environment != env.EnvLocal {
+			return nil, fmt.Errorf("%s : %w", fn, ErrNoTransport)
+		}
+		transport = "stdout"
+	}
+	switch transport {
+	case "stdout":
+		return NewWriter(os.Stdout, cfg.From), nil
+	case "file":
+		f, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", fn, err)
+		}
+		return NewWriter(f, cfg.From), nil
+	case "smtp":
+		return NewSMTP(cfg), nil
+	}
+	return nil, fmt.Errorf("%s : %w: %q", fn, ErrUnknownTransport, transport)
+}
+
+// SMTP delivers messages through a mail server, authenticating with PLAIN
+// auth when a username is configured.
+type SMTP struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTP(cfg *config.Mail) *SMTP {
+	m := &SMTP{
+		addr: net.JoinHostPort(cfg.SMTP.Host, cfg.SMTP.Port),
+		from: cfg.From,
+	}
+	if cfg.SMTP.Username != "" {
+		m.auth = smtp.PlainAuth("", cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.Host)
+	}
+	return m
+}
+
+func (m *SMTP) Send(ctx context.Context, msg Message) error {
+	const fn = "lib.mailer.SMTP.Send"
+	data, err := format(m.from, msg)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+
+	// smtp.SendMail takes no context, so the caller is released when it is
+	// done and the delivery finishes in the background.
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, data)
+	}()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+// Writer writes messages to w instead of delivering them. It is meant for
+// local development and tests.
+type Writer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+}
+
+func NewWriter(w io.Writer, from string) *Writer {
+	return &Writer{w: w, from: from}
+}
+
+func (m *Writer) Send(_ context.Context, msg Message) error {
+	const fn = "lib.mailer.Writer.Send"
+	data, err := format(m.from, msg)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := fmt.Fprintf(m.w, "%s\r\n\r\n", data); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+// format renders msg as a plain text RFC 5322 message.
+func format(from string, msg Message) ([]byte, error) {
+	for _, h := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(h, "\r\n") {
+			return nil, errHeaderInjection
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,25 @@
+package onetime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"go_url_chortener_api/internal/lib/random"
+)
+
+const tokenLength = 32
+
+// New returns a random single-use token to hand to the user and the hash
+// to store in its place.
+func New() (token string, hash string, err error) {
+	token, err = random.NewToken(tokenLength)
+	if err != nil {
+		return "", "", err
+	}
+	return token, Hash(token), nil
+}
+
+// Hash is what tokens are stored and looked up by.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,129 @@
+package ownership
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_url_chortener_api/internal/domain"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// RecordPrefix is prepended to the host to get the name of the TXT
+	// record checked by the DNS challenge.
+	RecordPrefix = "_shortener-challenge."
+	// RecordValuePrefix is prepended to the token in the TXT record value.
+	RecordValuePrefix = "shortener-verification="
+	// FilePath is where the HTTP challenge expects the token to be served.
+	FilePath = "/.well-known/shortener-verification.txt"
+
+	maxFileSize = 1024
+)
+
+var (
+	ErrNotVerified   = errors.New("challenge not found")
+	ErrUnknownMethod = errors.New("unknown verification method")
+)
+
+// Resolver looks up TXT records. *net.Resolver satisfies it.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// HTTPClient fetches the challenge file. *http.Client satisfies it.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Verifier checks that the owner of a domain published its verification
+// token either in DNS or on the web server behind the domain.
+type Verifier struct {
+	resolver Resolver
+	client   HTTPClient
+}
+
+func NewVerifier(resolver Resolver, client HTTPClient) *Verifier {
+	return &Verifier{
+		resolver: resolver,
+		client:   client,
+	}
+}
+
+func (v *Verifier) Verify(ctx context.Context, d *domain.Domain) error {
+	const fn = "lib.ownership.Verify"
+
+	var err error
+	switch d.Method {
+	case domain.VerifyDNS:
+		err = v.verifyDNS(ctx, d.Host, d.Token)
+	case domain.VerifyHTTP:
+		err = v.verifyHTTP(ctx, d.Host, d.Token)
+	default:
+		err = ErrUnknownMethod
+	}
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+func (v *Verifier) verifyDNS(ctx context.Context, host, token string) error {
+	records, err := v.resolver.LookupTXT(ctx, RecordPrefix+host)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotVerified, err)
+	}
+	for _, record := range records {
+		if strings.TrimSpace(record) == RecordValuePrefix+token {
+			return nil
+		}
+	}
+	return ErrNotVerified
+}
+
+func (v *Verifier) verifyHTTP(ctx context.Context, host, token string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+host+FilePath, nil)
+	if err != nil {
+		return err
+	}
+	res, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotVerified, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: status %d", ErrNotVerified, res.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxFileSize))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotVerified, err)
+	}
+	if strings.TrimSpace(string(body)) != token {
+		return ErrNotVerified
+	}
+	return nil
+}
+
+// Instructions describe what the owner has to publish for the challenge.
+type Instructions struct {
+	Method string `json:"method"`
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+}
+
+func InstructionsFor(d *domain.Domain) Instructions {
+	if d.Method == domain.VerifyHTTP {
+		return Instructions{
+			Method: d.Method,
+			Name:   "http://" + d.Host + FilePath,
+			Value:  d.Token,
+		}
+	}
+	return Instructions{
+		Method: d.Method,
+		Name:   RecordPrefix + d.Host,
+		Value:  RecordValuePrefix + d.Token,
+	}
+}
@@ -0,0 +1,76 @@
+package ownership
+
+import (
+	"context"
+	"errors"
+	"go_url_chortener_api/internal/domain"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeResolver map[string][]string
+
+func (f fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	records, ok := f[name]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return records, nil
+}
+
+func TestVerifyDNS(t *testing.T) {
+	resolver := fakeResolver{
+		"_shortener-challenge.go.acme.com": {"v=spf1 -all", "shortener-verification=secret"},
+	}
+	v := NewVerifier(resolver, http.DefaultClient)
+
+	tests := []struct {
+		name  string
+		host  string
+		token string
+		ok    bool
+	}{
+		{"matching record", "go.acme.com", "secret", true},
+		{"wrong token", "go.acme.com", "other", false},
+		{"missing record", "go.example.com", "secret", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Verify(context.Background(), &domain.Domain{
+				Host: tt.host, Method: domain.VerifyDNS, Token: tt.token,
+			})
+			if tt.ok && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrNotVerified) {
+				t.Fatalf("err = %v, want ErrNotVerified", err)
+			}
+		})
+	}
+}
+
+func TestVerifyHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != FilePath {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("secret\n"))
+	}))
+	defer srv.Close()
+
+	host := strings.TrimPrefix(srv.URL, "http://")
+	v := NewVerifier(fakeResolver{}, srv.Client())
+
+	err := v.Verify(context.Background(), &domain.Domain{Host: host, Method: domain.VerifyHTTP, Token: "secret"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = v.Verify(context.Background(), &domain.Domain{Host: host, Method: domain.VerifyHTTP, Token: "other"})
+	if !errors.Is(err, ErrNotVerified) {
+		t.Fatalf("err = %v, want ErrNotVerified", err)
+	}
+}
@@ -0,0 +1,68 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// prefixLength is the number of hex characters of the SHA-1 that name the
+// file holding the hash, as in the Pwned Passwords range API.
+const prefixLength = 5
+
+// BreachedDir looks passwords up in a local copy of a breached password
+// corpus, split by hash prefix the way the Pwned Passwords range API
+// serves it: the file named after the first five hex characters of the
+// SHA-1 (e.g. "5BAA6") lists the remaining 35 as "SUFFIX:COUNT" lines.
+// Only the bucket of the password is read, never the whole corpus.
+type BreachedDir struct {
+	dir string
+}
+
+func NewBreachedDir(dir string) (*BreachedDir, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	return &BreachedDir{dir: dir}, nil
+}
+
+func (b *BreachedDir) Breached(password string) (bool, error) {
+	const fn = "lib.password.BreachedDir.Breached"
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	f, err := os.Open(filepath.Join(b.dir, prefix))
+	// no file means no breached password with that prefix
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s : %w", fn, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		// padding entries of the range API have a count of 0
+		if strings.EqualFold(line, suffix) && count != "0" {
+			return true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("%s : %w", fn, err)
+	}
+	return false, nil
+}
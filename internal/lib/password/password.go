@@ -0,0 +1,191 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"go_url_chortener_api/internal/config"
+	resp "go_url_chortener_api/internal/lib/api/response"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrMismatch = errors.New("passwords do not match")
+
+// Violation codes, stable for clients to tell the rules apart.
+const (
+	CodeTooShort      = "too_short"
+	CodeTooLong       = "too_long"
+	CodeMissingUpper  = "missing_upper"
+	CodeMissingLower  = "missing_lower"
+	CodeMissingDigit  = "missing_digit"
+	CodeMissingSymbol = "missing_symbol"
+	CodeRepeated      = "repeated_characters"
+	CodeContainsEmail = "contains_email"
+	CodeBreached      = "breached"
+)
+
+// CodeInvalidPassword is the response code of a password that breaks the
+// policy; the violations say how.
+const CodeInvalidPassword = "invalid_password"
+
+// minEmailPart is the shortest local part of an email that is looked for
+// in the password, shorter ones give false positives.
+const minEmailPart = 3
+
+// Violation is one rule of the policy a password breaks.
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type Response struct {
+	resp.Response
+	Violations []Violation `json:"violations"`
+}
+
+// ViolationsResponse lists every rule the password breaks at once.
+func ViolationsResponse(violations []Violation) Response {
+	return Response{
+		Response:   resp.ErrorCode(CodeInvalidPassword, "password does not meet the requirements"),
+		Violations: violations,
+	}
+}
+
+// BreachChecker tells whether a password appeared in a known breach.
+type BreachChecker interface {
+	Breached(password string) (bool, error)
+}
+
+// Policy is the set of rules account passwords must follow.
+type Policy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// MaxRepeat is the longest run of one character allowed, 0 for any.
+	MaxRepeat int
+	// Breaches is optional.
+	Breaches BreachChecker
+}
+
+// NewPolicy returns the configured policy. The breached password check is
+// only enabled when a directory of prefix files is configured.
+func NewPolicy(cfg *config.PasswordPolicy) (*Policy, error) {
+	p := &Policy{
+		MinLength:     cfg.MinLength,
+		MaxLength:     cfg.MaxLength,
+		RequireUpper:  cfg.RequireUpper,
+		RequireLower:  cfg.RequireLower,
+		RequireDigit:  cfg.RequireDigit,
+		RequireSymbol: cfg.RequireSymbol,
+		MaxRepeat:     cfg.MaxRepeat,
+	}
+	if cfg.BreachedDir != "" {
+		b, err := NewBreachedDir(cfg.BreachedDir)
+		if err != nil {
+			return nil, fmt.Errorf("lib.password.NewPolicy : %w", err)
+		}
+		p.Breaches = b
+	}
+	return p, nil
+}
+
+// Equal checks that the password and its confirmation match.
+func Equal(p1, p2 string) error {
+	if p1 != p2 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+// Check returns every rule the password of the account with email breaks.
+// The error is only set when the breach check itself failed.
+func (p *Policy) Check(password string, email string) ([]Violation, error) {
+	var violations []Violation
+	add := func(code, format string, args ...any) {
+		violations = append(violations, Violation{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		add(CodeTooShort, "password must contain at least %d characters", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		add(CodeTooLong, "password must contain at most %d characters", p.MaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, el := range password {
+		switch {
+		case unicode.IsUpper(el):
+			upper = true
+		case unicode.IsLower(el):
+			lower = true
+		case unicode.IsNumber(el):
+			digit = true
+		case unicode.IsSymbol(el) || unicode.IsPunct(el):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		add(CodeMissingUpper, "password must contain at least one uppercase character")
+	}
+	if p.RequireLower && !lower {
+		add(CodeMissingLower, "password must contain at least one lowercase character")
+	}
+	if p.RequireDigit && !digit {
+		add(CodeMissingDigit, "password must contain at least one number")
+	}
+	if p.RequireSymbol && !symbol {
+		add(CodeMissingSymbol, "password must contain at least one special symbol")
+	}
+
+	if p.MaxRepeat > 0 && longestRun(password) > p.MaxRepeat {
+		add(CodeRepeated, "password must not repeat a character more than %d times in a row", p.MaxRepeat)
+	}
+	if containsEmail(password, email) {
+		add(CodeContainsEmail, "password must not contain the email address")
+	}
+
+	if p.Breaches != nil {
+		breached, err := p.Breaches.Breached(password)
+		if err != nil {
+			return nil, err
+		}
+		if breached {
+			add(CodeBreached, "password appeared in a data breach, choose another one")
+		}
+	}
+	return violations, nil
+}
+
+func longestRun(s string) int {
+	longest, run := 0, 0
+	var prev rune
+	for i, el := range []rune(s) {
+		if i > 0 && el == prev {
+			run++
+		} else {
+			run = 1
+		}
+		prev = el
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+func containsEmail(password, email string) bool {
+	if email == "" {
+		return false
+	}
+	password = strings.ToLower(password)
+	email = strings.ToLower(email)
+	local, _, _ := strings.Cut(email, "@")
+	return strings.Contains(password, email) ||
+		(utf8.RuneCountInString(local) >= minEmailPart && strings.Contains(password, local))
+}
@@ -0,0 +1,85 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func codes(violations []Violation) string {
+	var c []string
+	for _, v := range violations {
+		c = append(c, v.Code)
+	}
+	return strings.Join(c, ",")
+}
+
+func TestCheck(t *testing.T) {
+	p := &Policy{
+		MinLength:     8,
+		MaxLength:     16,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		MaxRepeat:     3,
+	}
+	tests := map[string]string{
+		"Str0ng!pass":         "",
+		"Sh0rt!":              CodeTooShort,
+		"Way-T00-Long-For-It": CodeTooLong,
+		"n0upper!abc":         CodeMissingUpper,
+		"No!numbers":          CodeMissingDigit,
+		"Caaaab1!x":           CodeRepeated,
+		"Caaab1!xy":           "",
+		"Alice-1234!":         CodeContainsEmail,
+		// every violation is reported at once
+		"aaaa": CodeTooShort + "," + CodeMissingUpper + "," + CodeMissingDigit + "," +
+			CodeMissingSymbol + "," + CodeRepeated,
+	}
+	for pw, want := range tests {
+		violations, err := p.Check(pw, "alice@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := codes(violations); got != want {
+			t.Errorf("%q: got %q, want %q", pw, got, want)
+		}
+	}
+
+	// short local parts are not looked for
+	if v, _ := p.Check("Str0ng!pass", "st@example.com"); len(v) != 0 {
+		t.Errorf("short local part: got %q", codes(v))
+	}
+}
+
+func TestBreachedDir(t *testing.T) {
+	dir := t.TempDir()
+	sum := sha1.Sum([]byte("P@ssw0rd"))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	padding := strings.Repeat("0", 35)
+	err := os.WriteFile(filepath.Join(dir, hash[:5]),
+		[]byte(padding+":0\r\n"+hash[5:]+":12\r\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := NewBreachedDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Policy{MinLength: 1, Breaches: b}
+
+	if v, err := p.Check("P@ssw0rd", ""); err != nil || codes(v) != CodeBreached {
+		t.Errorf("breached password: got %q %v", codes(v), err)
+	}
+	if v, err := p.Check("P@ssw0rd2", ""); err != nil || len(v) != 0 {
+		t.Errorf("other password: got %q %v", codes(v), err)
+	}
+	if _, err := NewBreachedDir(filepath.Join(dir, "missing")); err == nil {
+		t.Error("missing directory accepted")
+	}
+}
@@ -0,0 +1,83 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// Data and error correction codewords of "HELLO WORLD" as version 1-M.
+func TestReedSolomon(t *testing.T) {
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	got := rsRemainder(data, rsDivisor(len(want)))
+	if !bytes.Equal(got, want) {
+		t.Fatalf("ecc = %v, want %v", got, want)
+	}
+}
+
+func TestFormatInfo(t *testing.T) {
+	tests := []struct {
+		level Level
+		mask  int
+		want  int
+	}{
+		{Low, 0, 0b111011111000100},
+		{Low, 4, 0b110011000101111},
+		{Medium, 0, 0b101010000010010},
+		{Quartile, 7, 0b010101111101101},
+		{High, 3, 0b001100111010000},
+	}
+	for _, tt := range tests {
+		if got := formatInfo(tt.level, tt.mask); got != tt.want {
+			t.Errorf("formatInfo(%v, %d) = %015b, want %015b", tt.level, tt.mask, got, tt.want)
+		}
+	}
+}
+
+func TestVersionInfo(t *testing.T) {
+	if got, want := versionInfo(7), 0b000111110010010100; got != want {
+		t.Fatalf("versionInfo(7) = %018b, want %018b", got, want)
+	}
+	if got, want := versionInfo(40), 0b101000110001101001; got != want {
+		t.Fatalf("versionInfo(40) = %018b, want %018b", got, want)
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	tests := []struct {
+		version int
+		level   Level
+		want    int
+	}{
+		{1, Low, 19},
+		{1, High, 9},
+		{10, Medium, 216},
+		{40, Low, 2956},
+		{40, Quartile, 1666},
+		{40, High, 1276},
+	}
+	for _, tt := range tests {
+		if got := numDataCodewords(tt.version, tt.level); got != tt.want {
+			t.Errorf("numDataCodewords(%d, %v) = %d, want %d", tt.version, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeVersion(t *testing.T) {
+	c, err := Encode([]byte("https://example.com/abcdef"), Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Version != 2 || c.Size != 25 {
+		t.Fatalf("version = %d, size = %d, want 2 and 25", c.Version, c.Size)
+	}
+
+	if _, err := Encode(make([]byte, 2953), Low); err != nil {
+		t.Fatalf("unexpected error at full capacity: %v", err)
+	}
+	if _, err := Encode(make([]byte, 2954), Low); !errors.Is(err, ErrDataTooLong) {
+		t.Fatalf("err = %v, want ErrDataTooLong", err)
+	}
+}
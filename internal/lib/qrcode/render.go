@@ -0,0 +1,96 @@
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidColor = errors.New("invalid color")
+
+// RenderOptions control how a symbol is drawn. Size is the requested width
+// in pixels, rounded down to a whole number of pixels per module; Margin is
+// the quiet zone in modules.
+type RenderOptions struct {
+	Size       int
+	Margin     int
+	Foreground color.RGBA
+	Background color.RGBA
+}
+
+// PNG writes the symbol as a two-color paletted PNG.
+func (c *Code) PNG(w io.Writer, opts RenderOptions) error {
+	total := c.Size + 2*opts.Margin
+	scale := opts.Size / total
+	if scale < 1 {
+		scale = 1
+	}
+	dim := total * scale
+
+	img := image.NewPaletted(image.Rect(0, 0, dim, dim), color.Palette{opts.Background, opts.Foreground})
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			x0, y0 := (x+opts.Margin)*scale, (y+opts.Margin)*scale
+			for dy := 0; dy < scale; dy++ {
+				row := img.Pix[(y0+dy)*img.Stride:]
+				for dx := 0; dx < scale; dx++ {
+					row[x0+dx] = 1
+				}
+			}
+		}
+	}
+
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	return encoder.Encode(w, img)
+}
+
+// SVG writes the symbol as a single path scaled to Size pixels.
+func (c *Code) SVG(w io.Writer, opts RenderOptions) error {
+	total := c.Size + 2*opts.Margin
+
+	var path strings.Builder
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				fmt.Fprintf(&path, "M%d,%dh1v1h-1z", x+opts.Margin, y+opts.Margin)
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">
+<rect width="100%%" height="100%%" fill="%s"/>
+<path d="%s" fill="%s"/>
+</svg>
+`, opts.Size, opts.Size, total, total, HexColor(opts.Background), path.String(), HexColor(opts.Foreground))
+	return err
+}
+
+// ParseColor parses a hex color in the form RGB or RRGGBB, with or
+// without a leading #.
+func ParseColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return color.RGBA{}, ErrInvalidColor
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{}, ErrInvalidColor
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xFF}, nil
+}
+
+func HexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
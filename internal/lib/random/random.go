@@ -1,14 +1,16 @@
 package random
 
 import (
-	"math/rand"
+	"crypto/rand"
+	"encoding/hex"
+	mathRand "math/rand"
 	"time"
 )
 
 const charSet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
 func NewRandomString(length int) string {
-	rnd := rand.New(rand.NewSource(time.Now().Unix()))
+	rnd := mathRand.New(mathRand.NewSource(time.Now().Unix()))
 
 	alias := make([]byte, length)
 	for i := range alias {
@@ -16,3 +18,13 @@ func NewRandomString(length int) string {
 	}
 	return string(alias)
 }
+
+// NewToken returns a hex encoded string of n cryptographically secure
+// random bytes, suitable for secrets handed out to users.
+func NewToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
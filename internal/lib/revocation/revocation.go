@@ -0,0 +1,119 @@
+package revocation
+
+import (
+	"go_url_chortener_api/internal/lib/clock"
+	"sync"
+	"time"
+)
+
+// Store keeps access tokens that must no longer be accepted before they
+// expire: single tokens by their jti, every token of a session, and every
+// token of a user issued before a point in time. postgres.Storage
+// implements it for deployments running several instances.
+type Store interface {
+	RevokeToken(jti string, expiresAt time.Time) error
+	IsTokenRevoked(jti string) (bool, error)
+	// RevokeSessionTokens rejects the tokens of the session until expiresAt,
+	// when the last of them has expired.
+	RevokeSessionTokens(sessionId int, expiresAt time.Time) error
+	IsSessionRevoked(sessionId int) (bool, error)
+	RevokeTokensBefore(userId int, t time.Time) error
+	// TokensRevokedBefore returns the zero time if the user has no watermark.
+	TokensRevokedBefore(userId int) (time.Time, error)
+}
+
+// sweepInterval limits how often expired entries are dropped.
+const sweepInterval = time.Minute
+
+// Memory is a Store for a single instance.
+type Memory struct {
+	mu        sync.Mutex
+	clk       clock.Clock
+	maxAge    time.Duration
+	tokens    map[string]time.Time
+	sessions  map[int]time.Time
+	users     map[int]time.Time
+	lastSweep time.Time
+}
+
+// NewMemory returns an empty store. maxAge is the lifetime of the longest
+// lived token; watermarks older than that cannot match anything and are
+// dropped.
+func NewMemory(clk clock.Clock, maxAge time.Duration) *Memory {
+	return &Memory{
+		clk:      clk,
+		maxAge:   maxAge,
+		tokens:   make(map[string]time.Time),
+		sessions: make(map[int]time.Time),
+		users:    make(map[int]time.Time),
+	}
+}
+
+func (m *Memory) RevokeToken(jti string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep()
+	m.tokens[jti] = expiresAt
+	return nil
+}
+
+func (m *Memory) IsTokenRevoked(jti string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.tokens[jti]
+	return ok, nil
+}
+
+func (m *Memory) RevokeSessionTokens(sessionId int, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep()
+	m.sessions[sessionId] = expiresAt
+	return nil
+}
+
+func (m *Memory) IsSessionRevoked(sessionId int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.sessions[sessionId]
+	return ok, nil
+}
+
+func (m *Memory) RevokeTokensBefore(userId int, t time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep()
+	if t.After(m.users[userId]) {
+		m.users[userId] = t
+	}
+	return nil
+}
+
+func (m *Memory) TokensRevokedBefore(userId int) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.users[userId], nil
+}
+
+func (m *Memory) sweep() {
+	now := m.clk.Now()
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	for jti, exp := range m.tokens {
+		if now.After(exp) {
+			delete(m.tokens, jti)
+		}
+	}
+	for id, exp := range m.sessions {
+		if now.After(exp) {
+			delete(m.sessions, id)
+		}
+	}
+	for id, t := range m.users {
+		if now.Sub(t) > m.maxAge {
+			delete(m.users, id)
+		}
+	}
+	m.lastSweep = now
+}
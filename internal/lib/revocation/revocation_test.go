@@ -0,0 +1,44 @@
+package revocation
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func TestMemorySweep(t *testing.T) {
+	clk := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	m := NewMemory(clk, 15*time.Minute)
+
+	_ = m.RevokeToken("a", clk.now.Add(5*time.Minute))
+	_ = m.RevokeTokensBefore(1, clk.now)
+
+	if ok, _ := m.IsTokenRevoked("a"); !ok {
+		t.Fatal("token not revoked")
+	}
+	if wm, _ := m.TokensRevokedBefore(1); !wm.Equal(clk.now) {
+		t.Fatalf("got watermark %v", wm)
+	}
+	// an older watermark does not move it back
+	_ = m.RevokeTokensBefore(1, clk.now.Add(-time.Hour))
+	if wm, _ := m.TokensRevokedBefore(1); !wm.Equal(clk.now) {
+		t.Fatalf("watermark moved back to %v", wm)
+	}
+
+	start := clk.now
+	clk.now = clk.now.Add(20 * time.Minute)
+	_ = m.RevokeToken("b", clk.now.Add(time.Minute))
+
+	if ok, _ := m.IsTokenRevoked("a"); ok {
+		t.Error("expired token was kept")
+	}
+	if wm, _ := m.TokensRevokedBefore(1); !wm.IsZero() {
+		t.Errorf("stale watermark %v was kept, set at %v", wm, start)
+	}
+	if ok, _ := m.IsTokenRevoked("b"); !ok {
+		t.Error("token not revoked")
+	}
+}
@@ -0,0 +1,124 @@
+package throttle
+
+import (
+	"go_url_chortener_api/internal/lib/clock"
+	"sync"
+	"time"
+)
+
+// sweepInterval limits how often forgotten entries are dropped.
+const sweepInterval = time.Minute
+
+// maxShift keeps the doubling delay from overflowing before it is capped.
+const maxShift = 30
+
+type Policy struct {
+	// Threshold failures are allowed freely. Each failure after that makes
+	// the next attempt wait, starting at BaseDelay and doubling up to
+	// MaxDelay.
+	Threshold int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// LockoutThreshold failures lock the key out for LockoutDuration, and
+	// every failure after that locks it again. Zero disables the lockout.
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	// ResetAfter without failures forgets the key's past failures.
+	ResetAfter time.Duration
+}
+
+type entry struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+// Throttle slows down repeated failures per key, such as an account or a
+// client IP. It is kept in memory, so each instance counts on its own.
+type Throttle struct {
+	mu        sync.Mutex
+	clk       clock.Clock
+	policy    Policy
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+func New(clk clock.Clock, policy Policy) *Throttle {
+	return &Throttle{
+		clk:     clk,
+		policy:  policy,
+		entries: make(map[string]*entry),
+	}
+}
+
+// Wait returns how long the key has to wait before its next attempt, zero
+// if it may try now.
+func (t *Throttle) Wait(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.entries[key]
+	if !ok {
+		return 0
+	}
+	if wait := e.blockedUntil.Sub(t.clk.Now()); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// Fail records a failed attempt of the key.
+func (t *Throttle) Fail(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.clk.Now()
+	t.sweep(now)
+
+	e, ok := t.entries[key]
+	if !ok || t.forgotten(e, now) {
+		e = &entry{}
+		t.entries[key] = e
+	}
+	e.failures++
+	e.lastFailure = now
+
+	p := t.policy
+	switch {
+	case p.LockoutThreshold > 0 && e.failures >= p.LockoutThreshold:
+		e.blockedUntil = now.Add(p.LockoutDuration)
+	case e.failures > p.Threshold:
+		shift := e.failures - p.Threshold - 1
+		if shift > maxShift {
+			shift = maxShift
+		}
+		delay := p.BaseDelay << shift
+		if delay > p.MaxDelay || delay <= 0 {
+			delay = p.MaxDelay
+		}
+		e.blockedUntil = now.Add(delay)
+	}
+}
+
+// Reset forgets the failures of the key, after a successful attempt.
+func (t *Throttle) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.entries, key)
+}
+
+func (t *Throttle) forgotten(e *entry, now time.Time) bool {
+	return now.After(e.blockedUntil) && now.Sub(e.lastFailure) > t.policy.ResetAfter
+}
+
+func (t *Throttle) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < sweepInterval {
+		return
+	}
+	t.lastSweep = now
+	for key, e := range t.entries {
+		if t.forgotten(e, now) {
+			delete(t.entries, key)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func TestBackoffAndLockout(t *testing.T) {
+	clk := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	th := New(clk, Policy{
+		Threshold:        2,
+		BaseDelay:        time.Second,
+		MaxDelay:         4 * time.Second,
+		LockoutThreshold: 6,
+		LockoutDuration:  time.Hour,
+		ResetAfter:       24 * time.Hour,
+	})
+
+	// failures 1-2 are free, then 1s, 2s, 4s, then 4s again (capped)
+	want := []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second}
+	for i, w := range want {
+		th.Fail("a")
+		if got := th.Wait("a"); got != w {
+			t.Fatalf("failure %d: got wait %v, want %v", i+1, got, w)
+		}
+		clk.now = clk.now.Add(th.Wait("a"))
+	}
+	if got := th.Wait("b"); got != 0 {
+		t.Errorf("other key: got wait %v", got)
+	}
+
+	th.Fail("a")
+	if got := th.Wait("a"); got != time.Hour {
+		t.Fatalf("lockout: got wait %v", got)
+	}
+	clk.now = clk.now.Add(time.Hour)
+	if got := th.Wait("a"); got != 0 {
+		t.Fatalf("after lockout: got wait %v", got)
+	}
+	th.Fail("a")
+	if got := th.Wait("a"); got != time.Hour {
+		t.Errorf("failure after lockout: got wait %v", got)
+	}
+
+	th.Reset("a")
+	th.Fail("a")
+	if got := th.Wait("a"); got != 0 {
+		t.Errorf("after reset: got wait %v", got)
+	}
+
+	// a day without failures forgets them
+	th.Fail("a")
+	th.Fail("a")
+	clk.now = clk.now.Add(25 * time.Hour)
+	th.Fail("a")
+	if got := th.Wait("a"); got != 0 {
+		t.Errorf("after ResetAfter: got wait %v", got)
+	}
+}
@@ -0,0 +1,87 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+// rfcSecret is the SHA-1 seed of the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeAt(t *testing.T) {
+	// RFC 6238 appendix B, truncated to 6 digits
+	tests := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range tests {
+		got, err := CodeAt(rfcSecret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%d: got %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk := &fakeClock{now: time.Unix(1700000000, 0)}
+	totp := New(clk)
+
+	c, _ := CodeAt(secret, clk.now)
+	step, ok := totp.Verify(secret, c, 0)
+	if !ok || step != Step(clk.now) {
+		t.Fatalf("current code: got %d %v", step, ok)
+	}
+	if _, ok := totp.Verify(secret, c, step); ok {
+		t.Error("code accepted twice")
+	}
+
+	// one period of drift either way is tolerated
+	prev, _ := CodeAt(secret, clk.now.Add(-Period))
+	if _, ok := totp.Verify(secret, prev, 0); !ok {
+		t.Error("previous code refused")
+	}
+	next, _ := CodeAt(secret, clk.now.Add(Period))
+	if _, ok := totp.Verify(secret, next, 0); !ok {
+		t.Error("next code refused")
+	}
+
+	clk.now = clk.now.Add(2 * Period)
+	if _, ok := totp.Verify(secret, c, 0); ok {
+		t.Error("stale code accepted")
+	}
+	for _, bad := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, ok := totp.Verify(secret, bad, 0); ok {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+func TestURI(t *testing.T) {
+	u, err := url.Parse(URI("Shortener", "a@b.c", "ABC"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Shortener:a@b.c" {
+		t.Errorf("got %s", u)
+	}
+	if q := u.Query(); q.Get("secret") != "ABC" || q.Get("issuer") != "Shortener" {
+		t.Errorf("got query %v", q)
+	}
+}
@@ -0,0 +1,75 @@
+package useragent
+
+import (
+	"bufio"
+	_ "embed"
+	"strings"
+)
+
+type Class int
+
+const (
+	Human Class = iota
+	Bot
+	Unfurler
+)
+
+func (c Class) String() string {
+	switch c {
+	case Bot:
+		return "bot"
+	case Unfurler:
+		return "unfurler"
+	default:
+		return "human"
+	}
+}
+
+var (
+	//go:embed bots.txt
+	botsFile string
+	//go:embed unfurlers.txt
+	unfurlersFile string
+
+	botSignatures      = parseSignatures(botsFile)
+	unfurlerSignatures = parseSignatures(unfurlersFile)
+)
+
+// Classify tells humans apart from crawlers and link previewers by the
+// User-Agent header. Unfurlers are checked first since most of them also
+// call themselves a bot. A missing User-Agent is treated as a bot.
+func Classify(ua string) Class {
+	ua = strings.ToLower(strings.TrimSpace(ua))
+	if ua == "" {
+		return Bot
+	}
+	if matchAny(ua, unfurlerSignatures) {
+		return Unfurler
+	}
+	if matchAny(ua, botSignatures) {
+		return Bot
+	}
+	return Human
+}
+
+func matchAny(ua string, signatures []string) bool {
+	for _, s := range signatures {
+		if strings.Contains(ua, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseSignatures(file string) []string {
+	var signatures []string
+	scanner := bufio.NewScanner(strings.NewReader(file))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		signatures = append(signatures, strings.ToLower(line))
+	}
+	return signatures
+}
@@ -0,0 +1,30 @@
+package useragent
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want Class
+	}{
+		{"chrome", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", Human},
+		{"safari on iphone", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1", Human},
+		{"firefox", "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0", Human},
+		{"googlebot", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", Bot},
+		{"curl", "curl/8.4.0", Bot},
+		{"empty", "", Bot},
+		{"blank", "   ", Bot},
+		{"slackbot", "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", Unfurler},
+		{"facebook", "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", Unfurler},
+		{"twitter", "Twitterbot/1.0", Unfurler},
+		{"upper case", "FACEBOOKEXTERNALHIT/1.1", Unfurler},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.ua); got != tt.want {
+				t.Errorf("Classify(%q) = %s, want %s", tt.ua, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/storage"
+)
+
+// SearchUsers returns users whose email contains q, newest first. An empty
+// q matches everyone.
+func (s *Storage) SearchUsers(q string, limit int, offset int) ([]domain.User, error) {
+	const fn = "storage.postgres.SearchUsers"
+
+	query := `SELECT ` + userColumns + ` FROM users
+				WHERE strpos(lower(email), lower($1)) > 0
+				ORDER BY id DESC LIMIT $2 OFFSET $3`
+	rows, err := s.db.Query(query, q, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	defer rows.Close()
+
+	users := make([]domain.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", fn, err)
+		}
+		users = append(users, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	return users, nil
+}
+
+func (s *Storage) SetUserDisabled(id int, disabled bool) error {
+	const fn = "storage.postgres.SetUserDisabled"
+	err := s.updateUser(`UPDATE users SET disabled=$2 WHERE id=$1`, id, disabled)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+func (s *Storage) SetPasswordResetRequired(id int, required bool) error {
+	const fn = "storage.postgres.SetPasswordResetRequired"
+	err := s.updateUser(`UPDATE users SET password_reset_required=$2 WHERE id=$1`, id, required)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+// updateUser runs an update of a single user and reports a missing one.
+func (s *Storage) updateUser(query string, args ...any) error {
+	res, err := s.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return storage.ErrUserNotFound
+	}
+	return nil
+}
+
+// SearchURLs returns links on any domain whose alias or destination
+// contains q, newest first.
+func (s *Storage) SearchURLs(q string, limit int, offset int) ([]domain.URL, error) {
+	const fn = "storage.postgres.SearchURLs"
+
+	query := `SELECT url.id, alias, url, title, clicks, url.created_at, expires_at, disabled,
+				COALESCE(url.user_id, 0), COALESCE(domain_id, 0), COALESCE(domains.host, '')
+				FROM url LEFT JOIN domains ON domains.id = url.domain_id
+				WHERE strpos(lower(alias), lower($1)) > 0 OR strpos(lower(url), lower($1)) > 0
+				ORDER BY url.id DESC LIMIT $2 OFFSET $3`
+	rows, err := s.db.Query(query, q, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	defer rows.Close()
+
+	urls := make([]domain.URL, 0)
+	for rows.Next() {
+		var u domain.URL
+		var expiresAt sql.NullTime
+		err := rows.Scan(&u.Id, &u.Alias, &u.URL, &u.Title, &u.Clicks, &u.CreatedAt, &expiresAt,
+			&u.Disabled, &u.UserId, &u.DomainId, &u.Host)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", fn, err)
+		}
+		if expiresAt.Valid {
+			u.ExpiresAt = &expiresAt.Time
+		}
+		urls = append(urls, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	return urls, nil
+}
+
+func (s *Storage) SetURLDisabled(id int, disabled bool) error {
+	const fn = "storage.postgres.SetURLDisabled"
+
+	res, err := s.db.Exec(`UPDATE url SET disabled=$2 WHERE id=$1`, id, disabled)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("%s : %w", fn, storage.ErrURLNotFound)
+	}
+	return nil
+}
+
+func (s *Storage) GetSystemStats() (*domain.SystemStats, error) {
+	const fn = "storage.postgres.GetSystemStats"
+
+	query := `SELECT
+				(SELECT count(*) FROM users),
+				(SELECT count(*) FROM users WHERE disabled),
+				(SELECT count(*) FROM url),
+				(SELECT count(*) FROM url WHERE disabled),
+				(SELECT COALESCE(sum(clicks), 0) FROM url),
+				(SELECT count(*) FROM domains),
+				(SELECT count(*) FROM pages),
+				(SELECT count(*) FROM session WHERE revoked_at IS NULL),
+				(SELECT count(*) FROM api_key)`
+	st := new(domain.SystemStats)
+	err := s.db.QueryRow(query).Scan(&st.Users, &st.DisabledUsers, &st.Links, &st.DisabledLinks,
+		&st.Clicks, &st.Domains, &st.Pages, &st.ActiveSessions, &st.APIKeys)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	return st, nil
+}
+
+func (s *Storage) SaveAuditEntry(e *domain.AuditEntry) error {
+	const fn = "storage.postgres.SaveAuditEntry"
+
+	query := `INSERT INTO audit_log(actor_id, action, target_id, query, status, ip)
+				VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`
+	err := s.db.QueryRow(query, nullInt(e.ActorId), e.Action, e.TargetId, e.Query, e.Status, e.IP).
+		Scan(&e.Id, &e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+// GetAuditLog returns audit entries, newest first.
+func (s *Storage) GetAuditLog(limit int, offset int) ([]domain.AuditEntry, error) {
+	const fn = "storage.postgres.GetAuditLog"
+
+	query := `SELECT id, COALESCE(actor_id, 0), action, target_id, query, status, ip, created_at
+				FROM audit_log ORDER BY id DESC LIMIT $1 OFFSET $2`
+	rows, err := s.db.Query(query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	defer rows.Close()
+
+	entries := make([]domain.AuditEntry, 0)
+	for rows.Next() {
+		var e domain.AuditEntry
+		err := rows.Scan(&e.Id, &e.ActorId, &e.Action, &e.TargetId, &e.Query, &e.Status, &e.IP, &e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", fn, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	return entries, nil
+}
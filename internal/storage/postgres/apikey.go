@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/storage"
+)
+
+func (s *Storage) SaveAPIKey(k *domain.APIKey) error {
+	const fn = "storage.postgres.SaveAPIKey"
+
+	query := `INSERT INTO api_key(user_id, name, prefix, hash, scopes, expires_at)
+				VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`
+	err := s.db.QueryRow(query, k.UserId, k.Name, k.Prefix, k.Hash, pq.Array(k.Scopes), k.ExpiresAt).
+		Scan(&k.Id, &k.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return fmt.Errorf("%s : %w", fn, storage.ErrAPIKeyExists)
+		}
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+func (s *Storage) GetAPIKeyByPrefix(prefix string) (*domain.APIKey, error) {
+	const fn = "storage.postgres.GetAPIKeyByPrefix"
+
+	// Keys of disabled accounts are treated as gone.
+	query := `SELECT k.id, k.user_id, k.name, k.prefix, k.hash, k.scopes, k.expires_at, k.last_used_at, k.created_at
+				FROM api_key k JOIN users u ON u.id = k.user_id
+				WHERE k.prefix=$1 AND NOT u.disabled`
+	k, err := scanAPIKey(s.db.QueryRow(query, prefix))
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	return k, nil
+}
+
+func (s *Storage) GetAPIKeysByUser(userId int) ([]domain.APIKey, error) {
+	const fn = "storage.postgres.GetAPIKeysByUser"
+
+	query := `SELECT id, user_id, name, prefix, hash, scopes, expires_at, last_used_at, created_at
+				FROM api_key WHERE user_id=$1 ORDER BY id`
+	rows, err := s.db.Query(query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	defer rows.Close()
+
+	keys := make([]domain.APIKey, 0)
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", fn, err)
+		}
+		keys = append(keys, *k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	return keys, nil
+}
+
+func (s *Storage) TouchAPIKey(id int) error {
+	const fn = "storage.postgres.TouchAPIKey"
+
+	if _, err := s.db.Exec(`UPDATE api_key SET last_used_at=now() WHERE id=$1`, id); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+func (s *Storage) DeleteAPIKey(id int, userId int) error {
+	const fn = "storage.postgres.DeleteAPIKey"
+
+	res, err := s.db.Exec(`DELETE FROM api_key WHERE id=$1 AND user_id=$2`, id, userId)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%s : %w", fn, storage.ErrAPIKeyNotFound)
+	}
+	return nil
+}
+
+func scanAPIKey(row scanner) (*domain.APIKey, error) {
+	k := new(domain.APIKey)
+	var expiresAt, lastUsedAt sql.NullTime
+	err := row.Scan(&k.Id, &k.UserId, &k.Name, &k.Prefix, &k.Hash, pq.Array(&k.Scopes),
+		&expiresAt, &lastUsedAt, &k.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		k.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		k.LastUsedAt = &lastUsedAt.Time
+	}
+	return k, nil
+}
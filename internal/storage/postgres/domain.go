@@ -0,0 +1,185 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/storage"
+)
+
+// SaveDomain adds a pending claim on the host. It fails with
+// storage.ErrDomainExists when the host is verified already or the user
+// claimed it before.
+func (s *Storage) SaveDomain(d *domain.Domain) error {
+	const fn = "storage.postgres.SaveDomain"
+
+	query := `INSERT INTO domains(host, user_id, method, token)
+				SELECT $1, $2, $3, $4
+				WHERE NOT EXISTS (SELECT 1 FROM domains WHERE host=$1 AND verified_at IS NOT NULL)
+				RETURNING id, created_at`
+	err := s.db.QueryRow(query, d.Host, d.UserId, d.Method, d.Token).Scan(&d.Id, &d.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s : %w", fn, storage.ErrDomainExists)
+	}
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return fmt.Errorf("%s : %w", fn, storage.ErrDomainExists)
+		}
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+func (s *Storage) GetDomain(id int) (*domain.Domain, error) {
+	const fn = "storage.postgres.GetDomain"
+
+	query := `SELECT id, host, user_id, method, token, verified_at, created_at
+				FROM domains WHERE id=$1`
+	d, err := scanDomain(s.db.QueryRow(query, id))
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	return d, nil
+}
+
+// GetDomainByHost returns the verified domain for host. Pending claims
+// are not returned since several users may hold one.
+func (s *Storage) GetDomainByHost(host string) (*domain.Domain, error) {
+	const fn = "storage.postgres.GetDomainByHost"
+
+	query := `SELECT id, host, user_id, method, token, verified_at, created_at
+				FROM domains WHERE host=$1 AND verified_at IS NOT NULL`
+	d, err := scanDomain(s.db.QueryRow(query, host))
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	return d, nil
+}
+
+func (s *Storage) GetDomainsByUser(userId int) ([]domain.Domain, error) {
+	const fn = "storage.postgres.GetDomainsByUser"
+
+	query := `SELECT id, host, user_id, method, token, verified_at, created_at
+				FROM domains WHERE user_id=$1 ORDER BY id`
+	rows, err := s.db.Query(query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	defer rows.Close()
+
+	domains := make([]domain.Domain, 0)
+	for rows.Next() {
+		d, err := scanDomain(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", fn, err)
+		}
+		domains = append(domains, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	return domains, nil
+}
+
+// MarkDomainVerified makes the claim the owner of its host and drops the
+// pending claims of other users. It fails with storage.ErrDomainExists
+// when another claim on the host was verified first.
+func (s *Storage) MarkDomainVerified(id int) error {
+	const fn = "storage.postgres.MarkDomainVerified"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	defer tx.Rollback()
+
+	var host string
+	err = tx.QueryRow(`UPDATE domains SET verified_at=now() WHERE id=$1 RETURNING host`, id).Scan(&host)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s : %w", fn, storage.ErrDomainNotFound)
+	}
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return fmt.Errorf("%s : %w", fn, storage.ErrDomainExists)
+		}
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+
+	query := `DELETE FROM domains WHERE host=$1 AND id<>$2 AND verified_at IS NULL`
+	if _, err := tx.Exec(query, host, id); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+// DeleteDomain removes a domain of the user together with its links.
+func (s *Storage) DeleteDomain(id int, userId int) error {
+	const fn = "storage.postgres.DeleteDomain"
+
+	query := `DELETE FROM domains WHERE id=$1 AND user_id=$2`
+	res, err := s.db.Exec(query, id, userId)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	if deleted < 1 {
+		return storage.ErrDomainNotFound
+	}
+	return nil
+}
+
+// domainIdByHost returns the id of the verified domain serving host,
+// or 0 for the default host.
+func (s *Storage) domainIdByHost(host string) (int, error) {
+	const fn = "storage.postgres.domainIdByHost"
+
+	if host == "" {
+		return 0, nil
+	}
+
+	var id int
+	query := `SELECT id FROM domains WHERE host=$1 AND verified_at IS NOT NULL`
+	err := s.db.QueryRow(query, domain.NormalizeHost(host)).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s : %w", fn, err)
+	}
+	return id, nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDomain(row scanner) (*domain.Domain, error) {
+	d := new(domain.Domain)
+	var verifiedAt sql.NullTime
+	err := row.Scan(&d.Id, &d.Host, &d.UserId, &d.Method, &d.Token, &verifiedAt, &d.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrDomainNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if verifiedAt.Valid {
+		d.VerifiedAt = &verifiedAt.Time
+	}
+	return d, nil
+}
+
+// nullInt stores zero ids as NULL.
+func nullInt(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
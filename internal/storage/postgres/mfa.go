@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"fmt"
+	"go_url_chortener_api/internal/storage"
+)
+
+// SetTOTPSecret starts an enrollment. The secret is only used for sign-in
+// once EnableTOTP confirmed it.
+func (s *Storage) SetTOTPSecret(userId int, secret string) error {
+	const fn = "storage.postgres.SetTOTPSecret"
+
+	query := `UPDATE users SET totp_secret=$2, totp_last_step=0 WHERE id=$1 AND NOT totp_enabled`
+	if err := s.updateUser(query, userId, secret); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+// EnableTOTP completes the enrollment and replaces the user's recovery
+// codes, of which only the hashes are kept.
+func (s *Storage) EnableTOTP(userId int, step int64, codeHashes []string) error {
+	const fn = "storage.postgres.EnableTOTP"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE users SET totp_enabled=TRUE, totp_last_step=$2
+				WHERE id=$1 AND totp_secret IS NOT NULL`, userId, step)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("%s : %w", fn, storage.ErrUserNotFound)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM recovery_code WHERE user_id=$1`, userId); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	for _, hash := range codeHashes {
+		_, err := tx.Exec(`INSERT INTO recovery_code(user_id, hash) VALUES ($1, $2)`, userId, hash)
+		if err != nil {
+			return fmt.Errorf("%s : %w", fn, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+// UseTOTPStep records that the code of the step was used. It fails with
+// storage.ErrTOTPCodeUsed when that or a later code was used already, which
+// also settles two sign-ins racing with the same code.
+func (s *Storage) UseTOTPStep(userId int, step int64) error {
+	const fn = "storage.postgres.UseTOTPStep"
+
+	res, err := s.db.Exec(`UPDATE users SET totp_last_step=$2 WHERE id=$1 AND totp_last_step < $2`, userId, step)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("%s : %w", fn, storage.ErrTOTPCodeUsed)
+	}
+	return nil
+}
+
+// UseRecoveryCode spends one of the user's recovery codes.
+func (s *Storage) UseRecoveryCode(userId int, hash string) error {
+	const fn = "storage.postgres.UseRecoveryCode"
+
+	res, err := s.db.Exec(`UPDATE recovery_code SET used_at=now()
+				WHERE user_id=$1 AND hash=$2 AND used_at IS NULL`, userId, hash)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("%s : %w", fn, storage.ErrRecoveryCodeNotFound)
+	}
+	return nil
+}
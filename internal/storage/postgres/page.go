@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/storage"
+)
+
+// SavePage inserts a new page or, when p.Id is set, replaces the page of
+// the same owner together with its list of links.
+func (s *Storage) SavePage(p *domain.Page) error {
+	const fn = "storage.postgres.SavePage"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	defer tx.Rollback()
+
+	if p.Id == 0 {
+		query := `INSERT INTO pages(user_id, slug, title, theme)
+					VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+		err = tx.QueryRow(query, p.UserId, p.Slug, p.Title, p.Theme).Scan(&p.Id, &p.CreatedAt)
+	} else {
+		query := `UPDATE pages SET slug=$1, title=$2, theme=$3
+					WHERE id=$4 AND user_id=$5 RETURNING created_at`
+		err = tx.QueryRow(query, p.Slug, p.Title, p.Theme, p.Id, p.UserId).Scan(&p.CreatedAt)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrPageNotFound
+	}
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return fmt.Errorf("%s : %w", fn, storage.ErrPageExists)
+		}
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM page_link WHERE page_id=$1`, p.Id); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	for _, l := range p.Links {
+		query := `INSERT INTO page_link(page_id, url_id, title, position) VALUES ($1, $2, $3, $4)`
+		if _, err := tx.Exec(query, p.Id, l.URLId, l.Title, l.Position); err != nil {
+			return fmt.Errorf("%s : %w", fn, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+func (s *Storage) GetPage(id int) (*domain.Page, error) {
+	const fn = "storage.postgres.GetPage"
+
+	query := `SELECT id, user_id, slug, title, theme, created_at FROM pages WHERE id=$1`
+	p, err := s.getPage(query, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	return p, nil
+}
+
+func (s *Storage) GetPageBySlug(slug string) (*domain.Page, error) {
+	const fn = "storage.postgres.GetPageBySlug"
+
+	query := `SELECT id, user_id, slug, title, theme, created_at FROM pages WHERE slug=$1`
+	p, err := s.getPage(query, slug)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	return p, nil
+}
+
+func (s *Storage) GetPagesByUser(userId int) ([]domain.Page, error) {
+	const fn = "storage.postgres.GetPagesByUser"
+
+	query := `SELECT id, user_id, slug, title, theme, created_at FROM pages
+				WHERE user_id=$1 ORDER BY id`
+	rows, err := s.db.Query(query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	defer rows.Close()
+
+	pages := make([]domain.Page, 0)
+	for rows.Next() {
+		var p domain.Page
+		if err := rows.Scan(&p.Id, &p.UserId, &p.Slug, &p.Title, &p.Theme, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s : %w", fn, err)
+		}
+		pages = append(pages, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+
+	for i := range pages {
+		if pages[i].Links, err = s.getPageLinks(pages[i].Id); err != nil {
+			return nil, fmt.Errorf("%s : %w", fn, err)
+		}
+	}
+	return pages, nil
+}
+
+func (s *Storage) DeletePage(id int, userId int) error {
+	const fn = "storage.postgres.DeletePage"
+
+	res, err := s.db.Exec(`DELETE FROM pages WHERE id=$1 AND user_id=$2`, id, userId)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	if deleted < 1 {
+		return storage.ErrPageNotFound
+	}
+	return nil
+}
+
+func (s *Storage) getPage(query string, arg any) (*domain.Page, error) {
+	p := new(domain.Page)
+	err := s.db.QueryRow(query, arg).Scan(&p.Id, &p.UserId, &p.Slug, &p.Title, &p.Theme, &p.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrPageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Links, err = s.getPageLinks(p.Id); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *Storage) getPageLinks(pageId int) ([]domain.PageLink, error) {
+	query := `SELECT page_link.id, url.id, url.alias, COALESCE(domains.host, ''), page_link.title, position
+				FROM page_link
+				JOIN url ON url.id = page_link.url_id
+				LEFT JOIN domains ON domains.id = url.domain_id
+				WHERE page_id=$1 ORDER BY position, page_link.id`
+	rows, err := s.db.Query(query, pageId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make([]domain.PageLink, 0)
+	for rows.Next() {
+		var l domain.PageLink
+		if err := rows.Scan(&l.Id, &l.URLId, &l.Alias, &l.Host, &l.Title, &l.Position); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/storage"
+	"time"
+)
+
+// SavePasswordReset stores the hash of a reset token. A user only has one
+// pending reset: requesting another invalidates the previous link.
+func (s *Storage) SavePasswordReset(userId int, hash string, expiresAt time.Time) error {
+	const fn = "storage.postgres.SavePasswordReset"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM password_reset WHERE user_id=$1 OR expires_at < now()`, userId)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	_, err = tx.Exec(`INSERT INTO password_reset(user_id, hash, expires_at) VALUES ($1, $2, $3)`,
+		userId, hash, expiresAt)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+// GetUserByPasswordReset returns the user an unexpired reset token was
+// issued to, without spending it.
+func (s *Storage) GetUserByPasswordReset(hash string) (*domain.User, error) {
+	const fn = "storage.postgres.GetUserByPasswordReset"
+
+	query := `SELECT ` + userColumns + ` FROM users
+				WHERE id = (SELECT user_id FROM password_reset WHERE hash=$1 AND expires_at > now())`
+	user, err := scanUser(s.db.QueryRow(query, hash))
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return nil, fmt.Errorf("%s : %w", fn, storage.ErrPasswordResetNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	return user, nil
+}
+
+// ResetPassword consumes the reset token, sets the new password and
+// revokes every session of the user. It returns the id of the user.
+func (s *Storage) ResetPassword(hash string, encPassword string) (int, error) {
+	const fn = "storage.postgres.ResetPassword"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s : %w", fn, err)
+	}
+	defer tx.Rollback()
+
+	// Deleting the row is what makes the token single-use: a concurrent
+	// reset with the same token finds nothing.
+	var userId int
+	err = tx.QueryRow(`DELETE FROM password_reset WHERE hash=$1 AND expires_at > now() RETURNING user_id`, hash).
+		Scan(&userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s : %w", fn, storage.ErrPasswordResetNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s : %w", fn, err)
+	}
+
+	_, err = tx.Exec(`UPDATE users SET enc_password=$2, password_reset_required=FALSE WHERE id=$1`,
+		userId, encPassword)
+	if err != nil {
+		return 0, fmt.Errorf("%s : %w", fn, err)
+	}
+	if err := revokeFamilies(tx, `user_id=$1`, userId); err != nil {
+		return 0, fmt.Errorf("%s : %w", fn, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s : %w", fn, err)
+	}
+	return userId, nil
+}
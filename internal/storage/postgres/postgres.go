@@ -2,14 +2,17 @@ package postgres
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"go_url_chortener_api/internal/config"
 	"go_url_chortener_api/internal/domain"
-	"go_url_chortener_api/internal/http-server/handlers/refresh"
 	"go_url_chortener_api/internal/storage"
 )
 
+// uniqueViolation is the postgres error code for a unique constraint violation
+const uniqueViolation = "23505"
+
 type Storage struct {
 	db *sql.DB
 }
@@ -59,6 +62,50 @@ func createTables(db *sql.DB) error {
 		return fmt.Errorf("%s : %w", fn, err)
 	}
 
+	_, err = db.Exec(`
+				ALTER TABLE url ADD COLUMN IF NOT EXISTS clicks BIGINT NOT NULL DEFAULT 0;
+				CREATE TABLE IF NOT EXISTS url_variant(
+				    id SERIAL PRIMARY KEY,
+				    url_id INT NOT NULL,
+				    url TEXT NOT NULL,
+				    weight INT NOT NULL,
+				    clicks BIGINT NOT NULL DEFAULT 0,
+				    CONSTRAINT variant_url_fk FOREIGN KEY (url_id) REFERENCES url(id) ON DELETE CASCADE
+				);
+				CREATE INDEX IF NOT EXISTS idx_variant_url ON url_variant(url_id);
+			`)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+
+	_, err = db.Exec(`
+				ALTER TABLE url ADD COLUMN IF NOT EXISTS starts_at TIMESTAMPTZ;
+				CREATE TABLE IF NOT EXISTS url_window(
+				    id SERIAL PRIMARY KEY,
+				    url_id INT NOT NULL,
+				    starts_at TIMESTAMPTZ,
+				    ends_at TIMESTAMPTZ,
+				    url TEXT NOT NULL,
+				    CONSTRAINT window_url_fk FOREIGN KEY (url_id) REFERENCES url(id) ON DELETE CASCADE
+				);
+				CREATE INDEX IF NOT EXISTS idx_window_url ON url_window(url_id);
+			`)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+
+	_, err = db.Exec(`
+				ALTER TABLE url ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '';
+				ALTER TABLE url ADD COLUMN IF NOT EXISTS preview BOOLEAN NOT NULL DEFAULT FALSE;
+				ALTER TABLE url ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+				ALTER TABLE url ADD COLUMN IF NOT EXISTS og_title TEXT NOT NULL DEFAULT '';
+				ALTER TABLE url ADD COLUMN IF NOT EXISTS og_description TEXT NOT NULL DEFAULT '';
+				ALTER TABLE url ADD COLUMN IF NOT EXISTS og_image TEXT NOT NULL DEFAULT '';
+			`)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+
 	_, err = db.Exec(`
 				CREATE TABLE IF NOT EXISTS users(
 				    id SERIAL PRIMARY KEY,
@@ -81,146 +128,446 @@ func createTables(db *sql.DB) error {
 	if err != nil {
 		return fmt.Errorf("%s : %w", fn, err)
 	}
-	return nil
-}
 
-func (s *Storage) SaveURL(urlToSave string, alias string) error {
-	const fn = "storage.postgres.SaveURL"
-	query := `INSERT INTO url(alias, url) VALUES ($1, $2)`
-	_, err := s.db.Exec(query, alias, urlToSave)
+	// Aliases are unique per domain; links on the default host have no domain.
+	_, err = db.Exec(`
+				CREATE TABLE IF NOT EXISTS domains(
+				    id SERIAL PRIMARY KEY,
+				    host TEXT NOT NULL UNIQUE,
+				    user_id INT NOT NULL,
+				    method VARCHAR(16) NOT NULL,
+				    token VARCHAR(64) NOT NULL,
+				    verified_at TIMESTAMPTZ,
+				    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				    CONSTRAINT domain_user_fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+				);
+				ALTER TABLE url ADD COLUMN IF NOT EXISTS user_id INT
+				    REFERENCES users(id) ON DELETE SET NULL;
+				ALTER TABLE url ADD COLUMN IF NOT EXISTS domain_id INT
+				    REFERENCES domains(id) ON DELETE CASCADE;
+				ALTER TABLE url DROP CONSTRAINT IF EXISTS url_alias_key;
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_url_domain_alias ON url(COALESCE(domain_id, 0), alias);
+			`)
 	if err != nil {
-		// TODO unique constraint handling
-		return fmt.Errorf("%s: %w", fn, err)
+		return fmt.Errorf("%s : %w", fn, err)
 	}
-	return nil
-}
 
-func (s *Storage) GetURL(alias string) (string, error) {
-	const fn = "storage.postgres.GetURL"
-	query := `SELECT url FROM url WHERE alias=$1 `
-	row := s.db.QueryRow(query, alias)
-	var url string
-	if err := row.Scan(&url); err != nil {
-		return "", fmt.Errorf("%s: %w", fn, err)
+	_, err = db.Exec(`
+				CREATE TABLE IF NOT EXISTS pages(
+				    id SERIAL PRIMARY KEY,
+				    user_id INT NOT NULL,
+				    slug VARCHAR(40) NOT NULL UNIQUE,
+				    title TEXT NOT NULL,
+				    theme VARCHAR(16) NOT NULL,
+				    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				    CONSTRAINT page_user_fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+				);
+				CREATE TABLE IF NOT EXISTS page_link(
+				    id SERIAL PRIMARY KEY,
+				    page_id INT NOT NULL,
+				    url_id INT NOT NULL,
+				    title TEXT NOT NULL,
+				    position INT NOT NULL,
+				    CONSTRAINT page_link_page_fk FOREIGN KEY (page_id) REFERENCES pages(id) ON DELETE CASCADE,
+				    CONSTRAINT page_link_url_fk FOREIGN KEY (url_id) REFERENCES url(id) ON DELETE CASCADE
+				);
+				CREATE INDEX IF NOT EXISTS idx_page_link_page ON page_link(page_id);
+			`)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
 	}
-	return url, nil
-}
 
-func (s *Storage) DeleteURL(alias string) error {
-	const fn = "storage.postgres.DeleteURL"
-	query := `DELETE FROM url WHERE alias=$1`
-	res, err := s.db.Exec(query, alias)
+	_, err = db.Exec(`
+				ALTER TABLE url ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+				ALTER TABLE url ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE;
+			`)
 	if err != nil {
-		return fmt.Errorf("%s: %w", fn, err)
+		return fmt.Errorf("%s : %w", fn, err)
 	}
 
-	deleted, err := res.RowsAffected()
+	// Refresh tokens are stored hashed. Rows from before families existed
+	// hold raw tokens that can no longer be matched, so they are dropped.
+	_, err = db.Exec(`
+				ALTER TABLE refresh_token ADD COLUMN IF NOT EXISTS family_id VARCHAR(32);
+				ALTER TABLE refresh_token ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+				ALTER TABLE refresh_token ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ NOT NULL DEFAULT now();
+				ALTER TABLE refresh_token ADD COLUMN IF NOT EXISTS rotated_at TIMESTAMPTZ;
+				ALTER TABLE refresh_token ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMPTZ;
+				DELETE FROM refresh_token WHERE family_id IS NULL;
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_token ON refresh_token(token);
+				CREATE INDEX IF NOT EXISTS idx_refresh_family ON refresh_token(family_id);
+			`)
 	if err != nil {
-		return fmt.Errorf("%s: %w", fn, err)
+		return fmt.Errorf("%s : %w", fn, err)
 	}
 
-	if deleted < 1 {
-		return storage.ErrURLNotFound
+	// A session is created on sign-in before its first refresh token, so
+	// families without one predate sessions and are dropped.
+	_, err = db.Exec(`
+				CREATE TABLE IF NOT EXISTS session(
+				    id SERIAL PRIMARY KEY,
+				    user_id INT NOT NULL,
+				    family_id VARCHAR(32) NOT NULL UNIQUE,
+				    user_agent TEXT NOT NULL,
+				    ip VARCHAR(64) NOT NULL,
+				    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				    last_used_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				    revoked_at TIMESTAMPTZ,
+				    CONSTRAINT session_user_fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+				);
+				CREATE INDEX IF NOT EXISTS idx_session_user ON session(user_id);
+				DELETE FROM refresh_token t WHERE NOT EXISTS
+				    (SELECT 1 FROM session s WHERE s.family_id = t.family_id);
+			`)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
 	}
-	return nil
-}
 
-func (s *Storage) GetUser(email string) (*domain.User, error) {
-	const fn = "storage.postgres.GetUser"
+	_, err = db.Exec(`
+				CREATE TABLE IF NOT EXISTS revoked_token(
+				    jti VARCHAR(64) PRIMARY KEY,
+				    expires_at TIMESTAMPTZ NOT NULL
+				);
+				CREATE TABLE IF NOT EXISTS token_watermark(
+				    user_id INT PRIMARY KEY,
+				    revoked_before TIMESTAMPTZ NOT NULL,
+				    CONSTRAINT watermark_user_fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+				);
+			`)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
 
-	query := `SELECT * FROM users WHERE email=$1`
-	row := s.db.QueryRow(query, email)
+	_, err = db.Exec(`
+				CREATE TABLE IF NOT EXISTS api_key(
+				    id SERIAL PRIMARY KEY,
+				    user_id INT NOT NULL,
+				    name TEXT NOT NULL,
+				    prefix VARCHAR(16) NOT NULL UNIQUE,
+				    hash VARCHAR(64) NOT NULL,
+				    scopes TEXT[] NOT NULL,
+				    expires_at TIMESTAMPTZ,
+				    last_used_at TIMESTAMPTZ,
+				    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				    CONSTRAINT api_key_user_fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+				);
+				CREATE INDEX IF NOT EXISTS idx_api_key_user ON api_key(user_id);
+			`)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
 
-	user := new(domain.User)
-	err := row.Scan(&user.Id, &user.Email, &user.EncPassword)
+	_, err = db.Exec(`
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user';
+			`)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
 
+	_, err = db.Exec(`
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE;
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+				CREATE TABLE IF NOT EXISTS audit_log(
+				    id SERIAL PRIMARY KEY,
+				    actor_id INT,
+				    action TEXT NOT NULL,
+				    target_id TEXT NOT NULL,
+				    query TEXT NOT NULL,
+				    status INT NOT NULL,
+				    ip VARCHAR(64) NOT NULL,
+				    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				    CONSTRAINT audit_actor_fk FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+				);
+			`)
 	if err != nil {
-		return nil, fmt.Errorf("%s : %w", fn, err)
+		return fmt.Errorf("%s : %w", fn, err)
 	}
-	return user, nil
-}
 
-func (s *Storage) GetUserById(id int) (*domain.User, error) {
-	const fn = "storage.postgres.GetUserById"
+	// Accounts that existed before email verification are treated as
+	// verified, so turning on require_verified_email does not lock them out.
+	_, err = db.Exec(`
+				DO $$
+				BEGIN
+				    IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+				                   WHERE table_name = 'users' AND column_name = 'email_verified_at') THEN
+				        ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+				        UPDATE users SET email_verified_at = created_at;
+				    END IF;
+				END $$;
+			`)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
 
-	query := `SELECT * FROM users WHERE id=$1`
+	_, err = db.Exec(`
+				CREATE TABLE IF NOT EXISTS password_reset(
+				    id SERIAL PRIMARY KEY,
+				    user_id INT NOT NULL,
+				    hash VARCHAR(64) NOT NULL UNIQUE,
+				    expires_at TIMESTAMPTZ NOT NULL,
+				    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				    CONSTRAINT password_reset_user_fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+				);
+			`)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
 
-	user := new(domain.User)
-	row := s.db.QueryRow(query, id)
+	_, err = db.Exec(`
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+				ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+				CREATE TABLE IF NOT EXISTS recovery_code(
+				    id SERIAL PRIMARY KEY,
+				    user_id INT NOT NULL,
+				    hash VARCHAR(64) NOT NULL,
+				    used_at TIMESTAMPTZ,
+				    CONSTRAINT recovery_code_user_fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+				);
+				CREATE INDEX IF NOT EXISTS idx_recovery_code_user ON recovery_code(user_id);
+			`)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
 
-	err := row.Scan(&user.Id, &user.Email, &user.EncPassword)
+	// A host belongs to whoever verifies it first. Pending claims of other
+	// users do not block anyone and are dropped once a claim is verified.
+	_, err = db.Exec(`
+				ALTER TABLE domains DROP CONSTRAINT IF EXISTS domains_host_key;
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_domains_verified_host ON domains(host)
+				    WHERE verified_at IS NOT NULL;
+				CREATE UNIQUE INDEX IF NOT EXISTS idx_domains_user_host ON domains(user_id, host);
+			`)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
 
+	_, err = db.Exec(`
+				CREATE TABLE IF NOT EXISTS revoked_session(
+				    session_id INT PRIMARY KEY,
+				    expires_at TIMESTAMPTZ NOT NULL
+				);
+			`)
 	if err != nil {
-		return nil, fmt.Errorf("%s : %w", fn, err)
+		return fmt.Errorf("%s : %w", fn, err)
 	}
-	return user, nil
+	return nil
 }
 
-func (s *Storage) SaveUser(user *domain.User) error {
-	const fn = "storage.postgres.SaveUser"
-	query := `INSERT INTO users(email, enc_password)
-				VALUES($1, $2);`
+func (s *Storage) SaveURL(u *domain.URL) error {
+	const fn = "storage.postgres.SaveURL"
 
-	if _, err := s.db.Exec(query, user.Email, user.EncPassword); err != nil {
-		return fmt.Errorf("%s : %w", err)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	query := `INSERT INTO url(alias, url, title, preview, starts_at, expires_at,
+				og_title, og_description, og_image, user_id, domain_id)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+	err = tx.QueryRow(query, u.Alias, u.URL, u.Title, u.Preview, u.StartsAt, u.ExpiresAt,
+		u.OG.Title, u.OG.Description, u.OG.Image, nullInt(u.UserId), nullInt(u.DomainId),
+	).Scan(&id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return fmt.Errorf("%s: %w", fn, storage.ErrURLExists)
+		}
+		return fmt.Errorf("%s: %w", fn, err)
 	}
 
-	return nil
-}
-
-func (s *Storage) SaveRefresh(token string, userId int) error {
-	const fn = "storage.postgres.SaveRefresh"
+	for _, v := range u.Variants {
+		query := `INSERT INTO url_variant(url_id, url, weight) VALUES ($1, $2, $3)`
+		if _, err := tx.Exec(query, id, v.URL, v.Weight); err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+	}
 
-	query := `INSERT INTO refresh_token (token, user_id)
-				VALUES ($1, $2)`
+	for _, win := range u.Windows {
+		query := `INSERT INTO url_window(url_id, starts_at, ends_at, url) VALUES ($1, $2, $3, $4)`
+		if _, err := tx.Exec(query, id, win.StartsAt, win.EndsAt, win.URL); err != nil {
+			return fmt.Errorf("%s: %w", fn, err)
+		}
+	}
 
-	if _, err := s.db.Exec(query, token, userId); err != nil {
-		return fmt.Errorf("%s : %w", fn, err)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
 	}
 	return nil
-
 }
 
-func (s *Storage) GetRefresh(userId int) (*refresh.Token, error) {
-	const fn = "storage.postgres.GetRefresh"
+// GetURL finds a link by alias on the given host. Hosts that are not a
+// verified custom domain resolve to links on the default host.
+func (s *Storage) GetURL(host string, alias string) (*domain.URL, error) {
+	const fn = "storage.postgres.GetURL"
 
-	query := `SELECT * FROM refresh_token WHERE user_id=$1`
+	domainId, err := s.domainIdByHost(host)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
 
-	row := s.db.QueryRow(query, userId)
+	query := `SELECT url.id, alias, url, title, preview, clicks, url.created_at, starts_at,
+				expires_at, disabled, og_title, og_description, og_image,
+				COALESCE(url.user_id, 0), COALESCE(domain_id, 0), COALESCE(domains.host, '')
+				FROM url LEFT JOIN domains ON domains.id = url.domain_id
+				WHERE alias=$1 AND COALESCE(domain_id, 0)=$2`
+	row := s.db.QueryRow(query, alias, domainId)
+
+	u := new(domain.URL)
+	var startsAt, expiresAt sql.NullTime
+	err = row.Scan(&u.Id, &u.Alias, &u.URL, &u.Title, &u.Preview, &u.Clicks, &u.CreatedAt, &startsAt,
+		&expiresAt, &u.Disabled, &u.OG.Title, &u.OG.Description, &u.OG.Image,
+		&u.UserId, &u.DomainId, &u.Host,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrURLNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
 
-	token := new(refresh.Token)
+	variants, err := s.getVariants(u.Id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	u.Variants = variants
 
-	if err := row.Scan(&token.Id, &token.Token, &token.UserId); err != nil {
-		return nil, fmt.Errorf("%s : %w", fn, err)
+	windows, err := s.getWindows(u.Id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	u.Windows = windows
+
+	if startsAt.Valid {
+		u.StartsAt = &startsAt.Time
 	}
-	return token, nil
+	if expiresAt.Valid {
+		u.ExpiresAt = &expiresAt.Time
+	}
+	return u, nil
 }
 
-func (s *Storage) DeleteRefresh(token string) error {
-	const fn = "storage.postgres.DeleteRefresh"
-	query := `DELETE FROM refresh_token WHERE token=$1`
-	if _, err := s.db.Exec(query, token); err != nil {
-		return fmt.Errorf("%s : %w", fn, err)
+// UpdateURL saves the editable metadata of an existing url.
+func (s *Storage) UpdateURL(u *domain.URL) error {
+	const fn = "storage.postgres.UpdateURL"
+	query := `UPDATE url SET title=$1, preview=$2, og_title=$3, og_description=$4, og_image=$5
+				WHERE id=$6 AND user_id=$7`
+	res, err := s.db.Exec(query, u.Title, u.Preview, u.OG.Title, u.OG.Description, u.OG.Image, u.Id, u.UserId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if updated < 1 {
+		return storage.ErrURLNotFound
 	}
 	return nil
 }
 
-func (s *Storage) DeleteRefreshByUserId(id int) error {
-	const fn = "storage.postgres.DeleteRefreshByUserId"
-	query := `DELETE FROM refresh_token WHERE user_id=$1`
-	_, err := s.db.Exec(query, id)
+func (s *Storage) getVariants(urlId int) ([]domain.Variant, error) {
+	const fn = "storage.postgres.getVariants"
+	query := `SELECT id, url_id, url, weight, clicks FROM url_variant WHERE url_id=$1 ORDER BY id`
+	rows, err := s.db.Query(query, urlId)
 	if err != nil {
-		return fmt.Errorf("%s : %w", fn, err)
+		return nil, fmt.Errorf("%s: %w", fn, err)
 	}
-	return nil
+	defer rows.Close()
+
+	var variants []domain.Variant
+	for rows.Next() {
+		var v domain.Variant
+		if err := rows.Scan(&v.Id, &v.URLId, &v.URL, &v.Weight, &v.Clicks); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		variants = append(variants, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	return variants, nil
+}
+
+func (s *Storage) getWindows(urlId int) ([]domain.Window, error) {
+	const fn = "storage.postgres.getWindows"
+	query := `SELECT id, url_id, starts_at, ends_at, url FROM url_window
+				WHERE url_id=$1 ORDER BY starts_at NULLS FIRST, id`
+	rows, err := s.db.Query(query, urlId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	defer rows.Close()
+
+	var windows []domain.Window
+	for rows.Next() {
+		var (
+			win      domain.Window
+			startsAt sql.NullTime
+			endsAt   sql.NullTime
+		)
+		if err := rows.Scan(&win.Id, &win.URLId, &startsAt, &endsAt, &win.URL); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		if startsAt.Valid {
+			win.StartsAt = &startsAt.Time
+		}
+		if endsAt.Valid {
+			win.EndsAt = &endsAt.Time
+		}
+		windows = append(windows, win)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	return windows, nil
 }
 
-func (s *Storage) UpdateRefresh(id int, token string) error {
-	const fn = "storage.postgres.UpdateRefresh"
-	query := `UPDATE refresh_token SET token=$1 WHERE id=$2`
-	if _, err := s.db.Exec(query, token, id); err != nil {
-		return fmt.Errorf("%s : %w", err)
+// IncrementClicks counts a visit of the url and, when variantId is set,
+// of the variant the visitor was sent to.
+func (s *Storage) IncrementClicks(urlId int, variantId int) error {
+	const fn = "storage.postgres.IncrementClicks"
+
+	query := `UPDATE url SET clicks = clicks + 1 WHERE id=$1`
+	if _, err := s.db.Exec(query, urlId); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if variantId == 0 {
+		return nil
+	}
+	query = `UPDATE url_variant SET clicks = clicks + 1 WHERE id=$1 AND url_id=$2`
+	if _, err := s.db.Exec(query, variantId, urlId); err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
 	}
 	return nil
+}
+
+// DeleteURL deletes the link only when it belongs to userId, so another
+// user's alias reads as not found.
+func (s *Storage) DeleteURL(host string, alias string, userId int) error {
+	const fn = "storage.postgres.DeleteURL"
 
+	domainId, err := s.domainIdByHost(host)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	query := `DELETE FROM url WHERE alias=$1 AND COALESCE(domain_id, 0)=$2 AND user_id=$3`
+	res, err := s.db.Exec(query, alias, domainId, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	if deleted < 1 {
+		return storage.ErrURLNotFound
+	}
+	return nil
 }
@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go_url_chortener_api/internal/http-server/handlers/refresh"
+	"go_url_chortener_api/internal/storage"
+)
+
+// SaveRefresh stores the first token of a family. Expired tokens of the
+// user are cleaned up on the way.
+func (s *Storage) SaveRefresh(t *refresh.Token) error {
+	const fn = "storage.postgres.SaveRefresh"
+
+	if _, err := s.db.Exec(`DELETE FROM refresh_token WHERE user_id=$1 AND expires_at < now()`, t.UserId); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+
+	query := `INSERT INTO refresh_token (token, user_id, family_id, created_at, expires_at)
+				VALUES ($1, $2, $3, $4, $5) RETURNING id`
+
+	err := s.db.QueryRow(query, t.Hash, t.UserId, t.FamilyId, t.CreatedAt, t.ExpiresAt).Scan(&t.Id)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+func (s *Storage) GetRefresh(hash string) (*refresh.Token, error) {
+	const fn = "storage.postgres.GetRefresh"
+
+	query := `SELECT id, token, user_id, family_id, created_at, expires_at, rotated_at, revoked_at
+				FROM refresh_token WHERE token=$1`
+
+	t := new(refresh.Token)
+	var rotatedAt, revokedAt sql.NullTime
+	err := s.db.QueryRow(query, hash).Scan(&t.Id, &t.Hash, &t.UserId, &t.FamilyId,
+		&t.CreatedAt, &t.ExpiresAt, &rotatedAt, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s : %w", fn, storage.ErrRefreshNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	if rotatedAt.Valid {
+		t.RotatedAt = &rotatedAt.Time
+	}
+	if revokedAt.Valid {
+		t.RevokedAt = &revokedAt.Time
+	}
+	return t, nil
+}
+
+// RotateRefresh marks the token as rotated out and stores its successor.
+// It fails with storage.ErrRefreshReused if the token was already rotated
+// or revoked, so only one of two concurrent refreshes wins.
+func (s *Storage) RotateRefresh(id int, next *refresh.Token) error {
+	const fn = "storage.postgres.RotateRefresh"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE refresh_token SET rotated_at=now()
+				WHERE id=$1 AND rotated_at IS NULL AND revoked_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("%s : %w", fn, storage.ErrRefreshReused)
+	}
+
+	query := `INSERT INTO refresh_token (token, user_id, family_id, created_at, expires_at)
+				VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	err = tx.QueryRow(query, next.Hash, next.UserId, next.FamilyId, next.CreatedAt, next.ExpiresAt).Scan(&next.Id)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+// RevokeRefreshFamily revokes every token of the family and ends the
+// session it belongs to. It returns the id of that session, or 0 when the
+// family has none.
+func (s *Storage) RevokeRefreshFamily(familyId string) (int, error) {
+	const fn = "storage.postgres.RevokeRefreshFamily"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s : %w", fn, err)
+	}
+	defer tx.Rollback()
+
+	var sessionId int
+	err = tx.QueryRow(`SELECT id FROM session WHERE family_id=$1`, familyId).Scan(&sessionId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s : %w", fn, err)
+	}
+
+	if err := revokeFamilies(tx, `family_id=$1`, familyId); err != nil {
+		return 0, fmt.Errorf("%s : %w", fn, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s : %w", fn, err)
+	}
+	return sessionId, nil
+}
+
+// revokeFamilies revokes the sessions matching where and their tokens.
+func revokeFamilies(tx *sql.Tx, where string, args ...any) error {
+	query := `UPDATE session SET revoked_at=now() WHERE revoked_at IS NULL AND ` + where
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+	query = `UPDATE refresh_token SET revoked_at=now() WHERE revoked_at IS NULL AND ` + where
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) DeleteRefresh(hash string) error {
+	const fn = "storage.postgres.DeleteRefresh"
+	query := `DELETE FROM refresh_token WHERE token=$1`
+	if _, err := s.db.Exec(query, hash); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+func (s *Storage) DeleteRefreshByUserId(id int) error {
+	const fn = "storage.postgres.DeleteRefreshByUserId"
+	query := `DELETE FROM refresh_token WHERE user_id=$1`
+	_, err := s.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
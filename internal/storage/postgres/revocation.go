@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+func (s *Storage) RevokeToken(jti string, expiresAt time.Time) error {
+	const fn = "storage.postgres.RevokeToken"
+
+	if _, err := s.db.Exec(`DELETE FROM revoked_token WHERE expires_at < now()`); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	query := `INSERT INTO revoked_token(jti, expires_at) VALUES ($1, $2)
+				ON CONFLICT (jti) DO NOTHING`
+	if _, err := s.db.Exec(query, jti, expiresAt); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+func (s *Storage) IsTokenRevoked(jti string) (bool, error) {
+	const fn = "storage.postgres.IsTokenRevoked"
+
+	var revoked bool
+	err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM revoked_token WHERE jti=$1)`, jti).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("%s : %w", fn, err)
+	}
+	return revoked, nil
+}
+
+func (s *Storage) RevokeSessionTokens(sessionId int, expiresAt time.Time) error {
+	const fn = "storage.postgres.RevokeSessionTokens"
+
+	if _, err := s.db.Exec(`DELETE FROM revoked_session WHERE expires_at < now()`); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	query := `INSERT INTO revoked_session(session_id, expires_at) VALUES ($1, $2)
+				ON CONFLICT (session_id) DO UPDATE SET expires_at = EXCLUDED.expires_at`
+	if _, err := s.db.Exec(query, sessionId, expiresAt); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+func (s *Storage) IsSessionRevoked(sessionId int) (bool, error) {
+	const fn = "storage.postgres.IsSessionRevoked"
+
+	var revoked bool
+	query := `SELECT EXISTS(SELECT 1 FROM revoked_session WHERE session_id=$1)`
+	if err := s.db.QueryRow(query, sessionId).Scan(&revoked); err != nil {
+		return false, fmt.Errorf("%s : %w", fn, err)
+	}
+	return revoked, nil
+}
+
+func (s *Storage) RevokeTokensBefore(userId int, t time.Time) error {
+	const fn = "storage.postgres.RevokeTokensBefore"
+
+	query := `INSERT INTO token_watermark(user_id, revoked_before) VALUES ($1, $2)
+				ON CONFLICT (user_id) DO UPDATE
+				SET revoked_before = GREATEST(token_watermark.revoked_before, EXCLUDED.revoked_before)`
+	if _, err := s.db.Exec(query, userId, t); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+func (s *Storage) TokensRevokedBefore(userId int) (time.Time, error) {
+	const fn = "storage.postgres.TokensRevokedBefore"
+
+	var t time.Time
+	err := s.db.QueryRow(`SELECT revoked_before FROM token_watermark WHERE user_id=$1`, userId).Scan(&t)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s : %w", fn, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/storage"
+)
+
+func (s *Storage) SaveSession(sess *domain.Session) error {
+	const fn = "storage.postgres.SaveSession"
+
+	query := `INSERT INTO session(user_id, family_id, user_agent, ip)
+				VALUES ($1, $2, $3, $4) RETURNING id, created_at, last_used_at`
+	err := s.db.QueryRow(query, sess.UserId, sess.FamilyId, sess.UserAgent, sess.IP).
+		Scan(&sess.Id, &sess.CreatedAt, &sess.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+// TouchSession records a use of the session of the token family and
+// returns its id.
+func (s *Storage) TouchSession(familyId string, ip string) (int, error) {
+	const fn = "storage.postgres.TouchSession"
+
+	query := `UPDATE session SET last_used_at=now(), ip=$2
+				WHERE family_id=$1 AND revoked_at IS NULL RETURNING id`
+	var id int
+	err := s.db.QueryRow(query, familyId, ip).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s : %w", fn, storage.ErrSessionNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s : %w", fn, err)
+	}
+	return id, nil
+}
+
+// GetSessionsByUser returns the active sessions of the user, most recently
+// used first.
+func (s *Storage) GetSessionsByUser(userId int) ([]domain.Session, error) {
+	const fn = "storage.postgres.GetSessionsByUser"
+
+	query := `SELECT id, user_id, family_id, user_agent, ip, created_at, last_used_at
+				FROM session WHERE user_id=$1 AND revoked_at IS NULL
+				ORDER BY last_used_at DESC`
+	rows, err := s.db.Query(query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	defer rows.Close()
+
+	sessions := make([]domain.Session, 0)
+	for rows.Next() {
+		var sess domain.Session
+		err := rows.Scan(&sess.Id, &sess.UserId, &sess.FamilyId, &sess.UserAgent, &sess.IP,
+			&sess.CreatedAt, &sess.LastUsedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s : %w", fn, err)
+		}
+		sessions = append(sessions, sess)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends one session of the user along with its refresh tokens.
+func (s *Storage) RevokeSession(id int, userId int) error {
+	const fn = "storage.postgres.RevokeSession"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	defer tx.Rollback()
+
+	var familyId string
+	err = tx.QueryRow(`SELECT family_id FROM session
+				WHERE id=$1 AND user_id=$2 AND revoked_at IS NULL`, id, userId).Scan(&familyId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s : %w", fn, storage.ErrSessionNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+
+	if err := revokeFamilies(tx, `family_id=$1`, familyId); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+// RevokeSessionsByUser signs the user out everywhere.
+func (s *Storage) RevokeSessionsByUser(userId int) error {
+	const fn = "storage.postgres.RevokeSessionsByUser"
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	defer tx.Rollback()
+
+	if err := revokeFamilies(tx, `user_id=$1`, userId); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"go_url_chortener_api/internal/domain"
+	"go_url_chortener_api/internal/storage"
+)
+
+const userColumns = `id, email, enc_password, role, disabled, password_reset_required, email_verified_at,
+	totp_enabled, totp_secret, totp_last_step, created_at`
+
+func (s *Storage) GetUser(email string) (*domain.User, error) {
+	const fn = "storage.postgres.GetUser"
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE email=$1`
+	user, err := scanUser(s.db.QueryRow(query, email))
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	return user, nil
+}
+
+func (s *Storage) GetUserById(id int) (*domain.User, error) {
+	const fn = "storage.postgres.GetUserById"
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE id=$1`
+	user, err := scanUser(s.db.QueryRow(query, id))
+	if err != nil {
+		return nil, fmt.Errorf("%s : %w", fn, err)
+	}
+	return user, nil
+}
+
+func (s *Storage) SaveUser(user *domain.User) error {
+	const fn = "storage.postgres.SaveUser"
+	query := `INSERT INTO users(email, enc_password)
+				VALUES($1, $2) RETURNING id, role, created_at`
+
+	err := s.db.QueryRow(query, user.Email, user.EncPassword).Scan(&user.Id, &user.Role, &user.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+
+	return nil
+}
+
+// SetEmailVerified marks the address as verified, provided it is still
+// the user's address.
+func (s *Storage) SetEmailVerified(id int, email string) error {
+	const fn = "storage.postgres.SetEmailVerified"
+
+	query := `UPDATE users SET email_verified_at = COALESCE(email_verified_at, now())
+				WHERE id=$1 AND email=$2`
+	if err := s.updateUser(query, id, email); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+// UpdatePasswordHash replaces the stored hash of the same password, e.g.
+// after the hashing parameters changed. Unlike UpdatePassword it leaves the
+// sessions alone.
+func (s *Storage) UpdatePasswordHash(id int, encPassword string) error {
+	const fn = "storage.postgres.UpdatePasswordHash"
+
+	if err := s.updateUser(`UPDATE users SET enc_password=$2 WHERE id=$1`, id, encPassword); err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+// UpdatePassword sets the user's password and ends every session but the
+// one with keepSessionId.
+func (s *Storage) UpdatePassword(id int, encPassword string, keepSessionId int) error {
+	const fn = "storage.postgres.UpdatePassword"
+
+	err := s.updateCredentials(id, keepSessionId,
+		`UPDATE users SET enc_password=$2 WHERE id=$1`, id, encPassword)
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+// UpdateEmail changes the user's address, which then has to be verified
+// again, and ends every session but the one with keepSessionId.
+func (s *Storage) UpdateEmail(id int, email string, keepSessionId int) error {
+	const fn = "storage.postgres.UpdateEmail"
+
+	err := s.updateCredentials(id, keepSessionId,
+		`UPDATE users SET email=$2, email_verified_at=NULL WHERE id=$1`, id, email)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return fmt.Errorf("%s : %w", fn, storage.ErrUserExists)
+	}
+	if err != nil {
+		return fmt.Errorf("%s : %w", fn, err)
+	}
+	return nil
+}
+
+// updateCredentials runs the update of the user and revokes the other
+// sessions in one transaction.
+func (s *Storage) updateCredentials(id int, keepSessionId int, query string, args ...any) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	err = revokeFamilies(tx, `user_id=$1 AND family_id NOT IN (SELECT family_id FROM session WHERE id=$2)`,
+		id, keepSessionId)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func scanUser(row scanner) (*domain.User, error) {
+	user := new(domain.User)
+	var verifiedAt sql.NullTime
+	var totpSecret sql.NullString
+	err := row.Scan(&user.Id, &user.Email, &user.EncPassword, &user.Role,
+		&user.Disabled, &user.PasswordResetRequired, &verifiedAt,
+		&user.MFAEnabled, &totpSecret, &user.TOTPLastStep, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if verifiedAt.Valid {
+		user.EmailVerifiedAt = &verifiedAt.Time
+	}
+	user.TOTPSecret = totpSecret.String
+	return user, nil
+}
@@ -5,6 +5,28 @@ import (
 )
 
 var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserExists   = errors.New("user exists")
+
 	ErrURLNotFound = errors.New("url not found")
 	ErrURLExists   = errors.New("url exists")
+
+	ErrDomainNotFound = errors.New("domain not found")
+	ErrDomainExists   = errors.New("domain exists")
+
+	ErrPageNotFound = errors.New("page not found")
+	ErrPageExists   = errors.New("page exists")
+
+	ErrRefreshNotFound = errors.New("refresh token not found")
+	ErrRefreshReused   = errors.New("refresh token already rotated")
+
+	ErrSessionNotFound = errors.New("session not found")
+
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrAPIKeyExists   = errors.New("api key exists")
+
+	ErrPasswordResetNotFound = errors.New("password reset token not found")
+
+	ErrTOTPCodeUsed         = errors.New("totp code already used")
+	ErrRecoveryCodeNotFound = errors.New("recovery code not found")
 )